@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// NewACMEManager issues certificates on demand for handles which exist.
+func NewACMEManager(config Config) (*autocert.Manager, error) {
+	var cache autocert.Cache
+
+	switch {
+	case config.ACMECachePath != "":
+		cache = autocert.DirCache(config.ACMECachePath)
+	case config.ACMECacheTable != "":
+		if _, ok := ProviderAs[*PostgresHandles](config.Provider); !ok {
+			return nil, errors.New("the postgres provider is required to store certificates in a table (`ACME_CACHE_TABLE`)")
+		}
+
+		cache = NewPostgresCertificateCache(config.Provider, config.ACMECacheTable)
+	default:
+		return nil, errors.New("a directory (`ACME_CACHE_PATH`) or table (`ACME_CACHE_TABLE`) is required to store certificates")
+	}
+
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      cache,
+		HostPolicy: HandleExistsPolicy(config.Provider),
+		Email:      config.ACMEEmail,
+		Client:     &acme.Client{DirectoryURL: config.ACMEDirectoryURL},
+	}, nil
+}
+
+// HandleExistsPolicy only allows certificates to be issued for hostnames which
+// are handles with a Decentralized ID, so that certificates can't be requested
+// for arbitrary names on a supported domain.
+func HandleExistsPolicy(provider ProvidesDecentralizedIDs) autocert.HostPolicy {
+	return func(ctx context.Context, host string) error {
+		handle, err := HostnameToHandle(host)
+
+		if err != nil {
+			return err
+		}
+
+		if handle, err = HandleOnProvidedDomain(ctx, provider, handle); err != nil {
+			return err
+		}
+
+		did, err := GetValidDecentralizedIDForHandle(ctx, provider, handle)
+
+		if err != nil {
+			return err
+		}
+
+		if did == "" {
+			return DecentralizedIDNotFoundError{handle}
+		}
+
+		return nil
+	}
+}
+
+// PostgresCertificateCache stores certificates and the ACME account key in a
+// table created by `migrations/acme_certificates.sql`, using the pool of the
+// postgres provider.
+type PostgresCertificateCache struct {
+	provider ProvidesDecentralizedIDs
+	table    string
+}
+
+func NewPostgresCertificateCache(provider ProvidesDecentralizedIDs, table string) *PostgresCertificateCache {
+	return &PostgresCertificateCache{provider, table}
+}
+
+// pool is found on every use because the provider may have been reloaded.
+func (cache *PostgresCertificateCache) pool() (*pgxpool.Pool, error) {
+	pg, ok := ProviderAs[*PostgresHandles](cache.provider)
+
+	if !ok {
+		return nil, errors.New("certificates cannot be stored without the postgres provider")
+	}
+
+	return pg.pool, nil
+}
+
+func (cache *PostgresCertificateCache) Get(ctx context.Context, key string) ([]byte, error) {
+	query := fmt.Sprintf(
+		"select data from %s where key = $1",
+		pgx.Identifier{cache.table}.Sanitize(),
+	)
+
+	pool, err := cache.pool()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+
+	err = pool.QueryRow(ctx, query, key).Scan(&data)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, autocert.ErrCacheMiss
+	}
+
+	return data, err
+}
+
+func (cache *PostgresCertificateCache) Put(ctx context.Context, key string, data []byte) error {
+	query := fmt.Sprintf(
+		"insert into %s (key, data) values ($1, $2) on conflict (key) do update set data = excluded.data, updated_at = now()",
+		pgx.Identifier{cache.table}.Sanitize(),
+	)
+
+	pool, err := cache.pool()
+
+	if err != nil {
+		return err
+	}
+
+	_, err = pool.Exec(ctx, query, key, data)
+
+	return err
+}
+
+func (cache *PostgresCertificateCache) Delete(ctx context.Context, key string) error {
+	query := fmt.Sprintf(
+		"delete from %s where key = $1",
+		pgx.Identifier{cache.table}.Sanitize(),
+	)
+
+	pool, err := cache.pool()
+
+	if err != nil {
+		return err
+	}
+
+	_, err = pool.Exec(ctx, query, key)
+
+	return err
+}
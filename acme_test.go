@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestACMEProvider() *InMemoryProvider {
+	return NewInMemoryProvider(MapOfDids{
+		"alice.example.com": "did:plc:example001",
+	}, MapOfDomains{
+		"example.com": true,
+	})
+}
+
+func TestCertificatesAreOnlyIssuedForHandlesWhichExist(t *testing.T) {
+	policy := HandleExistsPolicy(newTestACMEProvider())
+
+	assert.NoError(t, policy(context.Background(), "alice.example.com"))
+
+	for _, host := range []string{"bob.example.com", "alice.example.net", "example.com", "localhost"} {
+		assert.Error(t, policy(context.Background(), host), "certificate would be issued for %s", host)
+	}
+}
+
+func TestACMEManagerIsConfigured(t *testing.T) {
+	manager, err := NewACMEManager(Config{
+		Provider:         newTestACMEProvider(),
+		ACMEDirectoryURL: "https://localhost:14000/dir",
+		ACMECachePath:    t.TempDir(),
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "https://localhost:14000/dir", manager.Client.DirectoryURL)
+	assert.Error(t, manager.HostPolicy(context.Background(), "bob.example.com"))
+}
+
+func TestACMEManagerRequiresPostgresProviderToStoreCertificatesInTable(t *testing.T) {
+	_, err := NewACMEManager(Config{
+		Provider:       newTestACMEProvider(),
+		ACMECacheTable: "acme_certificates",
+	})
+
+	assert.Error(t, err)
+}
+
+func TestACMERequiresHTTPSAndCertificateStorage(t *testing.T) {
+	tests := []map[string]string{
+		{"ACME_ENABLED": "true", "ACME_CACHE_PATH": "/tmp"},
+		{"ACME_ENABLED": "true", "TLS_ADDRESS": ":8443"},
+		{"ACME_ENABLED": "true", "TLS_ADDRESS": ":8443", "ACME_CACHE_TABLE": "acme_certificates"},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			t.Setenv("DID_PROVIDER", "memory")
+			t.Setenv("MEMORY_DIDS", "alice.example.com@did:plc:example001")
+			t.Setenv("MEMORY_DOMAINS", "example.com")
+
+			for key, value := range test {
+				t.Setenv(key, value)
+			}
+
+			_, err := ConfigFromEnvironment()
+
+			assert.Error(t, err, "%v", test)
+		})
+	}
+}
@@ -0,0 +1,546 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminHandle struct {
+	Handle string          `json:"handle"`
+	DID    DecentralizedID `json:"did"`
+}
+
+type AdminDomain struct {
+	Domain   Domain          `json:"domain"`
+	Settings *DomainSettings `json:"settings,omitempty"`
+}
+
+func AddAdminRoutes(admin *gin.RouterGroup, provider ProvidesDecentralizedIDs, manager ManagesDecentralizedIDs) {
+	admin.GET("/handles", ListHandles(manager))
+	admin.POST("/handles", CreateHandle(provider, manager))
+	admin.GET("/handles/:handle", ReadHandle(provider))
+	admin.PUT("/handles/:handle", UpdateHandle(provider, manager))
+	admin.DELETE("/handles/:handle", DeleteHandle(provider, manager))
+
+	admin.GET("/handles/:handle/document", ReadDIDDocument(provider))
+	admin.PUT("/handles/:handle/document", UpdateDIDDocument(provider))
+	admin.DELETE("/handles/:handle/document", DeleteDIDDocument(provider))
+
+	admin.GET("/domains", ListDomains(manager))
+	admin.POST("/domains", CreateDomain(provider, manager))
+	admin.GET("/domains/:domain", ReadDomain(provider))
+	admin.PUT("/domains/:domain", UpdateDomain(provider, manager))
+	admin.DELETE("/domains/:domain", DeleteDomain(manager))
+}
+
+func RequireBearerToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		bearer, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+
+		if !found || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", "Bearer")
+			abortWithAdminError(c, http.StatusUnauthorized, errors.New("a valid bearer token is required"))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func abortWithAdminError(c *gin.Context, status int, err error) {
+	switch {
+	case errors.Is(err, ErrProviderIsReadOnly):
+		status = http.StatusNotImplemented
+	case errors.Is(err, ErrChainCannotChangeHandle):
+		status = http.StatusConflict
+	}
+
+	if status >= http.StatusInternalServerError {
+		_ = c.Error(err)
+	}
+
+	c.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
+}
+
+// handleForAdmin parses and validates a handle on a domain provided by the
+// server, aborting the request when the handle cannot be used.
+func handleForAdmin(c *gin.Context, provider ProvidesDecentralizedIDs, hostname string) (Handle, bool) {
+	handle, err := HostnameToHandle(hostname)
+
+	if err != nil {
+		abortWithAdminError(c, http.StatusBadRequest, err)
+		return Handle{}, false
+	}
+
+	handle, err = HandleOnProvidedDomain(c, provider, handle)
+
+	if err != nil {
+		abortWithAdminError(c, http.StatusBadGateway, err)
+		return Handle{}, false
+	}
+
+	canProvide, err := provider.CanProvideForDomain(c, handle.Domain)
+
+	if err != nil {
+		abortWithAdminError(c, http.StatusBadGateway, err)
+		return Handle{}, false
+	}
+
+	if !canProvide {
+		abortWithAdminError(c, http.StatusBadRequest, &CannotGetHandelsFromDomainError{domain: handle.Domain})
+		return Handle{}, false
+	}
+
+	return handle, true
+}
+
+// existingDecentralizedID gets the Decentralized ID of a handle, aborting the
+// request when it cannot be found.
+func existingDecentralizedID(c *gin.Context, provider ProvidesDecentralizedIDs, handle Handle) (DecentralizedID, bool) {
+	did, err := provider.GetDecentralizedIDForHandle(c, handle)
+
+	if err != nil {
+		abortWithAdminError(c, http.StatusBadGateway, err)
+		return "", false
+	}
+
+	if did == "" {
+		abortWithAdminError(c, http.StatusNotFound, DecentralizedIDNotFoundError{handle: handle})
+		return "", false
+	}
+
+	return did, true
+}
+
+func ListHandles(manager ManagesDecentralizedIDs) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		dids, err := manager.ListDecentralizedIDs(c)
+
+		if err != nil {
+			abortWithAdminError(c, http.StatusBadGateway, err)
+			return
+		}
+
+		handles := make([]AdminHandle, 0, len(dids))
+
+		for hostname, did := range dids {
+			handles = append(handles, AdminHandle{Handle: string(hostname), DID: did})
+		}
+
+		slices.SortFunc(handles, func(a, b AdminHandle) int { return strings.Compare(a.Handle, b.Handle) })
+
+		c.JSON(http.StatusOK, handles)
+	}
+}
+
+func CreateHandle(provider ProvidesDecentralizedIDs, manager ManagesDecentralizedIDs) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body AdminHandle
+
+		if err := c.ShouldBindJSON(&body); err != nil {
+			abortWithAdminError(c, http.StatusBadRequest, err)
+			return
+		}
+
+		handle, ok := handleForAdmin(c, provider, body.Handle)
+
+		if !ok {
+			return
+		}
+
+		if err := ValidateDecentralizedID(body.DID); err != nil {
+			abortWithAdminError(c, http.StatusBadRequest, err)
+			return
+		}
+
+		existing, err := provider.GetDecentralizedIDForHandle(c, handle)
+
+		if err != nil {
+			abortWithAdminError(c, http.StatusBadGateway, err)
+			return
+		}
+
+		if existing != "" {
+			abortWithAdminError(c, http.StatusConflict, fmt.Errorf("%s already has a Decentralized ID", handle))
+			return
+		}
+
+		if err := manager.SetDecentralizedIDForHandle(c, handle, body.DID); err != nil {
+			abortWithAdminError(c, http.StatusBadGateway, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, AdminHandle{Handle: handle.String(), DID: body.DID})
+	}
+}
+
+func ReadHandle(provider ProvidesDecentralizedIDs) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		handle, ok := handleForAdmin(c, provider, c.Param("handle"))
+
+		if !ok {
+			return
+		}
+
+		did, ok := existingDecentralizedID(c, provider, handle)
+
+		if !ok {
+			return
+		}
+
+		c.JSON(http.StatusOK, AdminHandle{Handle: handle.String(), DID: did})
+	}
+}
+
+func UpdateHandle(provider ProvidesDecentralizedIDs, manager ManagesDecentralizedIDs) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body AdminHandle
+
+		if err := c.ShouldBindJSON(&body); err != nil {
+			abortWithAdminError(c, http.StatusBadRequest, err)
+			return
+		}
+
+		handle, ok := handleForAdmin(c, provider, c.Param("handle"))
+
+		if !ok {
+			return
+		}
+
+		if err := ValidateDecentralizedID(body.DID); err != nil {
+			abortWithAdminError(c, http.StatusBadRequest, err)
+			return
+		}
+
+		if _, ok := existingDecentralizedID(c, provider, handle); !ok {
+			return
+		}
+
+		if err := manager.SetDecentralizedIDForHandle(c, handle, body.DID); err != nil {
+			abortWithAdminError(c, http.StatusBadGateway, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, AdminHandle{Handle: handle.String(), DID: body.DID})
+	}
+}
+
+func DeleteHandle(provider ProvidesDecentralizedIDs, manager ManagesDecentralizedIDs) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		handle, ok := handleForAdmin(c, provider, c.Param("handle"))
+
+		if !ok {
+			return
+		}
+
+		if _, ok := existingDecentralizedID(c, provider, handle); !ok {
+			return
+		}
+
+		if err := manager.DeleteDecentralizedIDForHandle(c, handle); err != nil {
+			abortWithAdminError(c, http.StatusBadGateway, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func ReadDIDDocument(provider ProvidesDecentralizedIDs) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		handle, ok := handleForAdmin(c, provider, c.Param("handle"))
+
+		if !ok {
+			return
+		}
+
+		documents, ok := ProviderAs[ProvidesDIDDocuments](provider)
+
+		if !ok {
+			abortWithAdminError(c, http.StatusNotImplemented, errors.New("provider does not store DID documents"))
+			return
+		}
+
+		document, err := documents.GetDIDDocument(c, handle)
+
+		if err != nil {
+			abortWithAdminError(c, http.StatusBadGateway, err)
+			return
+		}
+
+		if document == nil {
+			abortWithAdminError(c, http.StatusNotFound, fmt.Errorf("No DID document found for %s", handle))
+			return
+		}
+
+		c.JSON(http.StatusOK, document)
+	}
+}
+
+// UpdateDIDDocument stores the did:web document of a handle, forgetting the
+// handle's cached Decentralized ID which may now be its did:web.
+func UpdateDIDDocument(provider ProvidesDecentralizedIDs) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		handle, ok := handleForAdmin(c, provider, c.Param("handle"))
+
+		if !ok {
+			return
+		}
+
+		var document DIDDocument
+
+		if err := c.ShouldBindJSON(&document); err != nil {
+			abortWithAdminError(c, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := ValidateDIDDocument(handle, document); err != nil {
+			abortWithAdminError(c, http.StatusBadRequest, err)
+			return
+		}
+
+		documents, ok := ProviderAs[ManagesDIDDocuments](provider)
+
+		if !ok {
+			abortWithAdminError(c, http.StatusNotImplemented, errors.New("provider does not store DID documents"))
+			return
+		}
+
+		if err := documents.SetDIDDocument(c, handle, document); err != nil {
+			abortWithAdminError(c, http.StatusBadGateway, err)
+			return
+		}
+
+		forgetCachedHandle(provider, handle)
+
+		c.JSON(http.StatusOK, document)
+	}
+}
+
+func DeleteDIDDocument(provider ProvidesDecentralizedIDs) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		handle, ok := handleForAdmin(c, provider, c.Param("handle"))
+
+		if !ok {
+			return
+		}
+
+		documents, ok := ProviderAs[ManagesDIDDocuments](provider)
+
+		if !ok {
+			abortWithAdminError(c, http.StatusNotImplemented, errors.New("provider does not store DID documents"))
+			return
+		}
+
+		if err := documents.DeleteDIDDocument(c, handle); err != nil {
+			abortWithAdminError(c, http.StatusBadGateway, err)
+			return
+		}
+
+		forgetCachedHandle(provider, handle)
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func forgetCachedHandle(provider ProvidesDecentralizedIDs, handle Handle) {
+	if cache, ok := ProviderAs[InvalidatesCache](provider); ok {
+		cache.ForgetHandle(Hostname(handle.String()))
+	}
+}
+
+func ListDomains(manager ManagesDecentralizedIDs) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		domains, err := manager.ListDomains(c)
+
+		if err != nil {
+			abortWithAdminError(c, http.StatusBadGateway, err)
+			return
+		}
+
+		response := make([]AdminDomain, 0, len(domains))
+
+		for _, domain := range domains {
+			response = append(response, AdminDomain{Domain: domain})
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+func CreateDomain(provider ProvidesDecentralizedIDs, manager ManagesDecentralizedIDs) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body AdminDomain
+
+		if err := c.ShouldBindJSON(&body); err != nil {
+			abortWithAdminError(c, http.StatusBadRequest, err)
+			return
+		}
+
+		domain := Domain(strings.ToLower(string(body.Domain)))
+
+		if err := ValidateDomain(domain); err != nil {
+			abortWithAdminError(c, http.StatusBadRequest, err)
+			return
+		}
+
+		canProvide, err := provider.CanProvideForDomain(c, domain)
+
+		if err != nil {
+			abortWithAdminError(c, http.StatusBadGateway, err)
+			return
+		}
+
+		if canProvide {
+			abortWithAdminError(c, http.StatusConflict, fmt.Errorf("Domain %s is already supported by this server", domain))
+			return
+		}
+
+		if err := manager.AddDomain(c, domain); err != nil {
+			abortWithAdminError(c, http.StatusBadGateway, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, AdminDomain{Domain: domain})
+	}
+}
+
+func ReadDomain(provider ProvidesDecentralizedIDs) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		domain := Domain(strings.ToLower(c.Param("domain")))
+
+		canProvide, err := provider.CanProvideForDomain(c, domain)
+
+		if err != nil {
+			abortWithAdminError(c, http.StatusBadGateway, err)
+			return
+		}
+
+		if !canProvide {
+			abortWithAdminError(c, http.StatusNotFound, &CannotGetHandelsFromDomainError{domain: domain})
+			return
+		}
+
+		body := AdminDomain{Domain: domain}
+
+		if settingsProvider, ok := ProviderAs[ProvidesDomainSettings](provider); ok {
+			settings, err := settingsProvider.GetDomainSettings(c, domain)
+
+			if err != nil {
+				abortWithAdminError(c, http.StatusBadGateway, err)
+				return
+			}
+
+			body.Settings = &settings
+		}
+
+		c.JSON(http.StatusOK, body)
+	}
+}
+
+// UpdateDomain adds a domain, replacing its settings when they are given.
+func UpdateDomain(provider ProvidesDecentralizedIDs, manager ManagesDecentralizedIDs) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		domain := Domain(strings.ToLower(c.Param("domain")))
+
+		if err := ValidateDomain(domain); err != nil {
+			abortWithAdminError(c, http.StatusBadRequest, err)
+			return
+		}
+
+		var body AdminDomain
+
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&body); err != nil {
+				abortWithAdminError(c, http.StatusBadRequest, err)
+				return
+			}
+		}
+
+		settingsManager, canManageSettings := ProviderAs[ManagesDomainSettings](provider)
+
+		if body.Settings != nil {
+			if err := body.Settings.Validate(); err != nil {
+				abortWithAdminError(c, http.StatusBadRequest, err)
+				return
+			}
+
+			if !canManageSettings {
+				abortWithAdminError(c, http.StatusNotImplemented, errors.New("provider does not store settings for domains"))
+				return
+			}
+		}
+
+		if err := manager.AddDomain(c, domain); err != nil {
+			abortWithAdminError(c, http.StatusBadGateway, err)
+			return
+		}
+
+		if body.Settings != nil {
+			if err := settingsManager.SetDomainSettings(c, domain, *body.Settings); err != nil {
+				abortWithAdminError(c, http.StatusBadGateway, err)
+				return
+			}
+		}
+
+		c.JSON(http.StatusOK, AdminDomain{Domain: domain, Settings: body.Settings})
+	}
+}
+
+// DeleteDomain only removes a domain which is stored, not one which is
+// provided for by a wildcard.
+func DeleteDomain(manager ManagesDecentralizedIDs) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		domain := Domain(strings.ToLower(c.Param("domain")))
+
+		domains, err := manager.ListDomains(c)
+
+		if err != nil {
+			abortWithAdminError(c, http.StatusBadGateway, err)
+			return
+		}
+
+		if !slices.Contains(domains, domain) {
+			abortWithAdminError(c, http.StatusNotFound, &CannotGetHandelsFromDomainError{domain: domain})
+			return
+		}
+
+		if err := manager.RemoveDomain(c, domain); err != nil {
+			abortWithAdminError(c, http.StatusBadGateway, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func ListVerifications(verifier *HandleVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		verifications := verifier.Results()
+
+		if c.Query("unverified") == "true" {
+			verifications = slices.DeleteFunc(verifications, func(verification Verification) bool {
+				return verification.Verified
+			})
+		}
+
+		c.JSON(http.StatusOK, verifications)
+	}
+}
+
+func ReloadConfiguration(reload func() error) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := reload(); err != nil {
+			abortWithAdminError(c, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
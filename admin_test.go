@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func NewTestAdminEnvironment(provider ProvidesDecentralizedIDs) *gin.Engine {
+	router := gin.New()
+
+	AddApplicationRoutes(router, Config{
+		Provider:   provider,
+		Logger:     testFileLogger,
+		AdminToken: "secret",
+	})
+
+	return router
+}
+
+func newTestAdminProvider() *InMemoryProvider {
+	return NewInMemoryProvider(map[Hostname]DecentralizedID{
+		"alice.example.com": "did:plc:example001",
+	}, map[Domain]bool{
+		"example.com": true,
+	})
+}
+
+func adminRequest(router *gin.Engine, method string, path string, body string) *httptest.ResponseRecorder {
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(res, req)
+
+	return res
+}
+
+func TestAdminRequiresBearerToken(t *testing.T) {
+	router := NewTestAdminEnvironment(newTestAdminProvider())
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/admin/handles", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	router.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+}
+
+func TestAdminListsHandles(t *testing.T) {
+	router := NewTestAdminEnvironment(newTestAdminProvider())
+
+	res := adminRequest(router, "GET", "/admin/handles", "")
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.JSONEq(t, `[{"handle": "alice.example.com", "did": "did:plc:example001"}]`, res.Body.String())
+}
+
+func TestAdminCreatesReadsUpdatesAndDeletesHandle(t *testing.T) {
+	router := NewTestAdminEnvironment(newTestAdminProvider())
+
+	res := adminRequest(router, "POST", "/admin/handles", `{"handle": "Bob.example.com", "did": "did:plc:example002"}`)
+	assert.Equal(t, http.StatusCreated, res.Code)
+	assert.JSONEq(t, `{"handle": "bob.example.com", "did": "did:plc:example002"}`, res.Body.String())
+
+	res = adminRequest(router, "PUT", "/admin/handles/bob.example.com", `{"did": "did:plc:example003"}`)
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	res = adminRequest(router, "GET", "/admin/handles/bob.example.com", "")
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.JSONEq(t, `{"handle": "bob.example.com", "did": "did:plc:example003"}`, res.Body.String())
+
+	res = adminRequest(router, "DELETE", "/admin/handles/bob.example.com", "")
+	assert.Equal(t, http.StatusNoContent, res.Code)
+
+	res = adminRequest(router, "GET", "/admin/handles/bob.example.com", "")
+	assert.Equal(t, http.StatusNotFound, res.Code)
+}
+
+func TestAdminRejectsInvalidHandles(t *testing.T) {
+	router := NewTestAdminEnvironment(newTestAdminProvider())
+
+	tests := []struct {
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{"POST", "/admin/handles", `{"handle": "alice.example.com", "did": "did:plc:example002"}`, http.StatusConflict},
+		{"POST", "/admin/handles", `{"handle": "bob.example.com", "did": "plc:example002"}`, http.StatusBadRequest},
+		{"POST", "/admin/handles", `{"handle": "bob", "did": "did:plc:example002"}`, http.StatusBadRequest},
+		{"POST", "/admin/handles", `{"handle": "bob.example.net", "did": "did:plc:example002"}`, http.StatusBadRequest},
+		{"PUT", "/admin/handles/carol.example.com", `{"did": "did:plc:example002"}`, http.StatusNotFound},
+		{"DELETE", "/admin/handles/carol.example.com", "", http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		res := adminRequest(router, test.method, test.path, test.body)
+
+		assert.Equal(t, test.expectedStatus, res.Code, "%s %s %s", test.method, test.path, test.body)
+	}
+}
+
+func TestAdminManagesDomains(t *testing.T) {
+	router := NewTestAdminEnvironment(newTestAdminProvider())
+
+	res := adminRequest(router, "POST", "/admin/domains", `{"domain": "example.net"}`)
+	assert.Equal(t, http.StatusCreated, res.Code)
+
+	res = adminRequest(router, "POST", "/admin/domains", `{"domain": "example.net"}`)
+	assert.Equal(t, http.StatusConflict, res.Code)
+
+	res = adminRequest(router, "GET", "/admin/domains", "")
+	assert.JSONEq(t, `[{"domain": "example.com"}, {"domain": "example.net"}]`, res.Body.String())
+
+	res = adminRequest(router, "DELETE", "/admin/domains/example.net", "")
+	assert.Equal(t, http.StatusNoContent, res.Code)
+
+	res = adminRequest(router, "GET", "/admin/domains/example.net", "")
+	assert.Equal(t, http.StatusNotFound, res.Code)
+}
+
+func TestAdminOnlyDeletesStoredDomains(t *testing.T) {
+	provider := NewInMemoryProvider(MapOfDids{}, MapOfDomains{"*.example.com": true})
+	router := NewTestAdminEnvironment(provider)
+
+	res := adminRequest(router, "DELETE", "/admin/domains/www.example.com", "")
+	assert.Equal(t, http.StatusNotFound, res.Code)
+
+	canProvide, _ := provider.CanProvideForDomain(context.Background(), "www.example.com")
+	assert.True(t, canProvide)
+
+	res = adminRequest(router, "DELETE", "/admin/domains/*.example.com", "")
+	assert.Equal(t, http.StatusNoContent, res.Code)
+
+	canProvide, _ = provider.CanProvideForDomain(context.Background(), "www.example.com")
+	assert.False(t, canProvide)
+}
+
+func TestAdminChangesThroughCacheAreVisibleImmediately(t *testing.T) {
+	router := NewTestAdminEnvironment(NewCachingProvider(newTestAdminProvider(), 10, time.Hour, time.Hour, time.Hour))
+
+	res := adminRequest(router, "GET", "/admin/handles/bob.example.com", "")
+	assert.Equal(t, http.StatusNotFound, res.Code)
+
+	adminRequest(router, "POST", "/admin/handles", `{"handle": "bob.example.com", "did": "did:plc:example002"}`)
+
+	res = adminRequest(router, "GET", "/admin/handles/bob.example.com", "")
+	assert.Equal(t, http.StatusOK, res.Code)
+}
+
+func TestAdminChangesConfiguredProviderWithCache(t *testing.T) {
+	t.Setenv("DID_PROVIDER", "memory")
+	t.Setenv("MEMORY_DIDS", "alice.example.com@did:plc:example001")
+	t.Setenv("MEMORY_DOMAINS", "example.com")
+	t.Setenv("CACHE_TTL", "1h")
+
+	config, err := ConfigFromEnvironment()
+	assert.NoError(t, err)
+
+	router := NewTestAdminEnvironment(config.Provider)
+
+	res := adminRequest(router, "POST", "/admin/handles", `{"handle": "bob.example.com", "did": "did:plc:example002"}`)
+	assert.Equal(t, http.StatusCreated, res.Code)
+
+	res = adminRequest(router, "GET", "/admin/handles/bob.example.com", "")
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	res = adminRequest(router, "POST", "/admin/domains", `{"domain": "example.net"}`)
+	assert.Equal(t, http.StatusCreated, res.Code)
+
+	res = adminRequest(router, "PUT", "/admin/domains/example.net", `{"settings": {"redirectStatus": 308}}`)
+	assert.Equal(t, http.StatusOK, res.Code)
+}
+
+func TestAdminReportsReadOnlyProvider(t *testing.T) {
+	readOnly := &countingProvider{ProvidesDecentralizedIDs: newTestAdminProvider()}
+	router := NewTestAdminEnvironment(NewCachingProvider(readOnly, 10, time.Hour, time.Hour, time.Hour))
+
+	res := adminRequest(router, "GET", "/admin/handles", "")
+
+	assert.Equal(t, http.StatusNotImplemented, res.Code)
+}
+
+func TestAdminManagesDomainSettings(t *testing.T) {
+	router := NewTestAdminEnvironment(newTestAdminProvider())
+
+	res := adminRequest(router, "PUT", "/admin/domains/example.net", `{"settings": {"redirectDidTemplate": "https://example.net/{did}", "redirectStatus": 308}}`)
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	res = adminRequest(router, "GET", "/admin/domains/example.net", "")
+	assert.JSONEq(t, `{"domain": "example.net", "settings": {"redirectDidTemplate": "https://example.net/{did}", "redirectStatus": 308}}`, res.Body.String())
+
+	res = adminRequest(router, "PUT", "/admin/domains/example.net", `{"settings": {"redirectStatus": 200}}`)
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+
+	res = adminRequest(router, "PUT", "/admin/domains/example.org", "")
+	assert.Equal(t, http.StatusOK, res.Code)
+}
@@ -0,0 +1,408 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// cacheLookupTimeout bounds a lookup shared by every request waiting for it,
+// which is not cancelled with the request which started it.
+const cacheLookupTimeout = 30 * time.Second
+
+type InvalidatesCache interface {
+	ForgetHandle(hostname Hostname)
+	ForgetDomain(domain Domain)
+	ForgetAll()
+}
+
+type CachingProvider struct {
+	provider          ProvidesDecentralizedIDs
+	ttl               time.Duration
+	handleNotFoundTTL time.Duration
+	domainNotFoundTTL time.Duration
+	handles           *expiringCache[DecentralizedID]
+	domains           *expiringCache[bool]
+	lookups           singleflight.Group
+}
+
+func NewCachingProvider(
+	provider ProvidesDecentralizedIDs,
+	size int,
+	ttl time.Duration,
+	handleNotFoundTTL time.Duration,
+	domainNotFoundTTL time.Duration,
+) *CachingProvider {
+	return &CachingProvider{
+		provider:          provider,
+		ttl:               ttl,
+		handleNotFoundTTL: handleNotFoundTTL,
+		domainNotFoundTTL: domainNotFoundTTL,
+		handles:           newExpiringCache[DecentralizedID](size),
+		domains:           newExpiringCache[bool](size),
+	}
+}
+
+func (cache *CachingProvider) GetDecentralizedIDForHandle(ctx context.Context, handle Handle) (DecentralizedID, error) {
+	if canProvide, ok := cache.domains.get(string(handle.Domain)); ok && !canProvide {
+		return "", &CannotGetHandelsFromDomainError{domain: handle.Domain}
+	}
+
+	if did, ok := cache.handles.get(handle.String()); ok {
+		return did, nil
+	}
+
+	result, err := cache.lookup(ctx, "handle:"+handle.String(), func(ctx context.Context) (interface{}, error) {
+		handles, domains := cache.handles.currentGeneration(), cache.domains.currentGeneration()
+		did, err := cache.provider.GetDecentralizedIDForHandle(ctx, handle)
+
+		if errors.Is(err, (*CannotGetHandelsFromDomainError)(nil)) {
+			cache.domains.setSince(string(handle.Domain), false, cache.domainNotFoundTTL, domains)
+		}
+
+		if err != nil {
+			return DecentralizedID(""), err
+		}
+
+		if did == "" {
+			cache.handles.setSince(handle.String(), did, cache.handleNotFoundTTL, handles)
+		} else {
+			cache.handles.setSince(handle.String(), did, cache.ttl, handles)
+		}
+
+		return did, nil
+	})
+
+	did, _ := result.(DecentralizedID)
+
+	return did, err
+}
+
+func (cache *CachingProvider) CanProvideForDomain(ctx context.Context, domain Domain) (bool, error) {
+	if canProvide, ok := cache.domains.get(string(domain)); ok {
+		return canProvide, nil
+	}
+
+	result, err := cache.lookup(ctx, "domain:"+string(domain), func(ctx context.Context) (interface{}, error) {
+		domains := cache.domains.currentGeneration()
+		canProvide, err := cache.provider.CanProvideForDomain(ctx, domain)
+
+		if err != nil {
+			return false, err
+		}
+
+		if canProvide {
+			cache.domains.setSince(string(domain), true, cache.ttl, domains)
+		} else {
+			cache.domains.setSince(string(domain), false, cache.domainNotFoundTTL, domains)
+		}
+
+		return canProvide, nil
+	})
+
+	canProvide, _ := result.(bool)
+
+	return canProvide, err
+}
+
+// lookup coalesces concurrent lookups of a key into one, which runs until it
+// finishes or times out even when the request which started it is cancelled.
+// Each request stops waiting when it is cancelled.
+func (cache *CachingProvider) lookup(ctx context.Context, key string, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	results := cache.lookups.DoChan(key, func() (interface{}, error) {
+		lookupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cacheLookupTimeout)
+		defer cancel()
+
+		return fn(lookupCtx)
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result := <-results:
+		return result.Val, result.Err
+	}
+}
+
+func (cache *CachingProvider) IsHealthy(ctx context.Context) (bool, string) {
+	healthy, status := cache.provider.IsHealthy(ctx)
+
+	return healthy, fmt.Sprintf("%s (caching %d handles and %d domains)", status, cache.handles.len(), cache.domains.len())
+}
+
+func (cache *CachingProvider) Close() error {
+	if closer, ok := cache.provider.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+func (cache *CachingProvider) Unwrap() ProvidesDecentralizedIDs {
+	return cache.provider
+}
+
+func (cache *CachingProvider) manager() (ManagesDecentralizedIDs, error) {
+	manager, ok := ProviderAs[ManagesDecentralizedIDs](cache.provider)
+
+	if !ok {
+		return nil, ErrProviderIsReadOnly
+	}
+
+	return manager, nil
+}
+
+func (cache *CachingProvider) ListDecentralizedIDs(ctx context.Context) (MapOfDids, error) {
+	manager, err := cache.manager()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return manager.ListDecentralizedIDs(ctx)
+}
+
+func (cache *CachingProvider) SetDecentralizedIDForHandle(ctx context.Context, handle Handle, did DecentralizedID) error {
+	manager, err := cache.manager()
+
+	if err != nil {
+		return err
+	}
+
+	defer cache.ForgetHandle(Hostname(handle.String()))
+
+	return manager.SetDecentralizedIDForHandle(ctx, handle, did)
+}
+
+func (cache *CachingProvider) DeleteDecentralizedIDForHandle(ctx context.Context, handle Handle) error {
+	manager, err := cache.manager()
+
+	if err != nil {
+		return err
+	}
+
+	defer cache.ForgetHandle(Hostname(handle.String()))
+
+	return manager.DeleteDecentralizedIDForHandle(ctx, handle)
+}
+
+func (cache *CachingProvider) ListDomains(ctx context.Context) ([]Domain, error) {
+	manager, err := cache.manager()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return manager.ListDomains(ctx)
+}
+
+func (cache *CachingProvider) AddDomain(ctx context.Context, domain Domain) error {
+	manager, err := cache.manager()
+
+	if err != nil {
+		return err
+	}
+
+	defer cache.ForgetDomain(domain)
+
+	return manager.AddDomain(ctx, domain)
+}
+
+func (cache *CachingProvider) RemoveDomain(ctx context.Context, domain Domain) error {
+	manager, err := cache.manager()
+
+	if err != nil {
+		return err
+	}
+
+	defer cache.ForgetDomain(domain)
+
+	return manager.RemoveDomain(ctx, domain)
+}
+
+func (cache *CachingProvider) ForgetHandle(hostname Hostname) {
+	cache.handles.delete(strings.ToLower(string(hostname)))
+}
+
+// ForgetDomain forgets whether a domain and the domains below it are supported
+// and every handle on them, as handles are only resolved for supported domains
+// and a domain or wildcard can change how the domains below it are provided.
+func (cache *CachingProvider) ForgetDomain(domain Domain) {
+	domainName := strings.TrimPrefix(strings.ToLower(string(domain)), "*.")
+
+	cache.domains.deleteMatching(func(key string) bool {
+		return key == domainName || strings.HasSuffix(key, "."+domainName)
+	})
+	cache.handles.deleteMatching(func(key string) bool {
+		return strings.HasSuffix(key, "."+domainName)
+	})
+}
+
+func (cache *CachingProvider) ForgetAll() {
+	cache.handles.purge()
+	cache.domains.purge()
+}
+
+type expiringCacheEntry[V any] struct {
+	key       string
+	value     V
+	expiresAt time.Time
+}
+
+// expiringCache is a least recently used cache of values which expire after a
+// time to live. A time to live of zero means values are not cached.
+type expiringCache[V any] struct {
+	mutex   sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+	now     func() time.Time
+	// generation changes whenever values are deleted, so that a value looked
+	// up before a deletion is not stored after it.
+	generation uint64
+}
+
+func newExpiringCache[V any](size int) *expiringCache[V] {
+	return &expiringCache[V]{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+func (cache *expiringCache[V]) get(key string) (V, bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	element, ok := cache.entries[key]
+
+	if !ok {
+		var zero V
+		return zero, false
+	}
+
+	entry := element.Value.(*expiringCacheEntry[V])
+
+	if !cache.now().Before(entry.expiresAt) {
+		cache.order.Remove(element)
+		delete(cache.entries, key)
+
+		var zero V
+		return zero, false
+	}
+
+	cache.order.MoveToFront(element)
+
+	return entry.value, true
+}
+
+func (cache *expiringCache[V]) set(key string, value V, ttl time.Duration) {
+	cache.setSince(key, value, ttl, cache.currentGeneration())
+}
+
+func (cache *expiringCache[V]) currentGeneration() uint64 {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	return cache.generation
+}
+
+// setSince stores a value unless values were deleted since the generation.
+func (cache *expiringCache[V]) setSince(key string, value V, ttl time.Duration, generation uint64) {
+	if ttl <= 0 || cache.size <= 0 {
+		return
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if cache.generation != generation {
+		return
+	}
+
+	entry := &expiringCacheEntry[V]{key: key, value: value, expiresAt: cache.now().Add(ttl)}
+
+	if element, ok := cache.entries[key]; ok {
+		element.Value = entry
+		cache.order.MoveToFront(element)
+		return
+	}
+
+	cache.entries[key] = cache.order.PushFront(entry)
+
+	for cache.order.Len() > cache.size {
+		oldest := cache.order.Back()
+		cache.order.Remove(oldest)
+		delete(cache.entries, oldest.Value.(*expiringCacheEntry[V]).key)
+	}
+}
+
+func (cache *expiringCache[V]) delete(key string) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.generation++
+
+	if element, ok := cache.entries[key]; ok {
+		cache.order.Remove(element)
+		delete(cache.entries, key)
+	}
+}
+
+func (cache *expiringCache[V]) deleteMatching(matches func(key string) bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.generation++
+
+	for key, element := range cache.entries {
+		if matches(key) {
+			cache.order.Remove(element)
+			delete(cache.entries, key)
+		}
+	}
+}
+
+func (cache *expiringCache[V]) purge() {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.generation++
+
+	cache.entries = make(map[string]*list.Element)
+	cache.order.Init()
+}
+
+// values lists the values which have not expired, most recently used first.
+func (cache *expiringCache[V]) values() []V {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	now := cache.now()
+	values := make([]V, 0, cache.order.Len())
+
+	for element := cache.order.Front(); element != nil; element = element.Next() {
+		entry := element.Value.(*expiringCacheEntry[V])
+
+		if now.Before(entry.expiresAt) {
+			values = append(values, entry.value)
+		}
+	}
+
+	return values
+}
+
+func (cache *expiringCache[V]) len() int {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	return cache.order.Len()
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countingProvider struct {
+	ProvidesDecentralizedIDs
+	handleLookups atomic.Int32
+	domainLookups atomic.Int32
+	release       chan struct{}
+}
+
+func (counting *countingProvider) GetDecentralizedIDForHandle(ctx context.Context, handle Handle) (DecentralizedID, error) {
+	did, err := counting.ProvidesDecentralizedIDs.GetDecentralizedIDForHandle(ctx, handle)
+
+	counting.handleLookups.Add(1)
+
+	// The lookup has already read the handle while it waits to be released.
+	if counting.release != nil {
+		<-counting.release
+
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+	}
+
+	return did, err
+}
+
+func (counting *countingProvider) CanProvideForDomain(ctx context.Context, domain Domain) (bool, error) {
+	counting.domainLookups.Add(1)
+	return counting.ProvidesDecentralizedIDs.CanProvideForDomain(ctx, domain)
+}
+
+func newTestCountingProvider() *countingProvider {
+	return &countingProvider{
+		ProvidesDecentralizedIDs: NewInMemoryProvider(map[Hostname]DecentralizedID{
+			"alice.example.com": "did:plc:example001",
+		}, map[Domain]bool{
+			"example.com": true,
+		}),
+	}
+}
+
+var testCacheAlice = Handle{Domain: "example.com", Username: "alice"}
+
+func TestCachedHandleIsLookedUpOnce(t *testing.T) {
+	backend := newTestCountingProvider()
+	cache := NewCachingProvider(backend, 10, time.Minute, time.Minute, time.Minute)
+
+	for range 3 {
+		did, err := cache.GetDecentralizedIDForHandle(context.Background(), testCacheAlice)
+		assert.Nil(t, err)
+		assert.Equal(t, DecentralizedID("did:plc:example001"), did)
+	}
+
+	assert.Equal(t, int32(1), backend.handleLookups.Load())
+}
+
+func TestUnknownHandleIsCachedWithNotFoundTTL(t *testing.T) {
+	backend := newTestCountingProvider()
+	cache := NewCachingProvider(backend, 10, time.Minute, 0, time.Minute)
+
+	carol := Handle{Domain: "example.com", Username: "carol"}
+
+	for range 2 {
+		did, err := cache.GetDecentralizedIDForHandle(context.Background(), carol)
+		assert.Nil(t, err)
+		assert.Equal(t, DecentralizedID(""), did)
+	}
+
+	assert.Equal(t, int32(2), backend.handleLookups.Load())
+}
+
+func TestUnknownDomainIsCachedWithNotFoundTTL(t *testing.T) {
+	backend := newTestCountingProvider()
+	cache := NewCachingProvider(backend, 10, time.Minute, time.Minute, time.Minute)
+
+	for _, username := range []Username{"alice", "bob"} {
+		_, err := cache.GetDecentralizedIDForHandle(context.Background(), Handle{Domain: "example.net", Username: username})
+		assert.True(t, errors.Is(err, (*CannotGetHandelsFromDomainError)(nil)))
+	}
+
+	canProvide, err := cache.CanProvideForDomain(context.Background(), "example.net")
+
+	assert.Nil(t, err)
+	assert.False(t, canProvide)
+	assert.Equal(t, int32(1), backend.handleLookups.Load())
+	assert.Equal(t, int32(0), backend.domainLookups.Load())
+}
+
+func TestCachedHandleExpires(t *testing.T) {
+	backend := newTestCountingProvider()
+	cache := NewCachingProvider(backend, 10, time.Minute, time.Minute, time.Minute)
+
+	now := time.Now()
+	cache.handles.now = func() time.Time { return now }
+
+	_, _ = cache.GetDecentralizedIDForHandle(context.Background(), testCacheAlice)
+
+	now = now.Add(2 * time.Minute)
+
+	_, _ = cache.GetDecentralizedIDForHandle(context.Background(), testCacheAlice)
+
+	assert.Equal(t, int32(2), backend.handleLookups.Load())
+}
+
+func TestCacheIsBoundedBySize(t *testing.T) {
+	cache := newExpiringCache[bool](2)
+
+	cache.set("a", true, time.Minute)
+	cache.set("b", true, time.Minute)
+	cache.get("a")
+	cache.set("c", true, time.Minute)
+
+	_, hasA := cache.get("a")
+	_, hasB := cache.get("b")
+
+	assert.Equal(t, 2, cache.len())
+	assert.True(t, hasA)
+	assert.False(t, hasB, "Least recently used entry was not evicted")
+}
+
+func TestConcurrentLookupsForHandleAreCoalesced(t *testing.T) {
+	backend := newTestCountingProvider()
+	backend.release = make(chan struct{})
+	cache := NewCachingProvider(backend, 10, time.Minute, time.Minute, time.Minute)
+
+	var lookups sync.WaitGroup
+
+	for range 10 {
+		lookups.Add(1)
+		go func() {
+			defer lookups.Done()
+			did, _ := cache.GetDecentralizedIDForHandle(context.Background(), testCacheAlice)
+			assert.Equal(t, DecentralizedID("did:plc:example001"), did)
+		}()
+	}
+
+	assert.Eventually(t, func() bool { return backend.handleLookups.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	close(backend.release)
+	lookups.Wait()
+
+	assert.Equal(t, int32(1), backend.handleLookups.Load())
+}
+
+func TestLookupInProgressWhenHandleIsForgottenIsNotCached(t *testing.T) {
+	backend := newTestCountingProvider()
+	backend.release = make(chan struct{})
+	cache := NewCachingProvider(backend, 10, time.Minute, time.Minute, time.Minute)
+
+	stale := make(chan DecentralizedID)
+
+	go func() {
+		did, _ := cache.GetDecentralizedIDForHandle(context.Background(), testCacheAlice)
+		stale <- did
+	}()
+
+	assert.Eventually(t, func() bool { return backend.handleLookups.Load() == 1 }, time.Second, time.Millisecond)
+
+	memory, _ := ProviderAs[*InMemoryProvider](backend.ProvidesDecentralizedIDs)
+	assert.NoError(t, memory.SetDecentralizedIDForHandle(context.Background(), testCacheAlice, "did:plc:example002"))
+	cache.ForgetHandle("alice.example.com")
+
+	close(backend.release)
+	assert.Equal(t, DecentralizedID("did:plc:example001"), <-stale)
+
+	did, err := cache.GetDecentralizedIDForHandle(context.Background(), testCacheAlice)
+
+	assert.NoError(t, err)
+	assert.Equal(t, DecentralizedID("did:plc:example002"), did)
+	assert.Equal(t, int32(2), backend.handleLookups.Load())
+}
+
+func TestCoalescedLookupIsNotCancelledWithTheFirstRequest(t *testing.T) {
+	backend := newTestCountingProvider()
+	backend.release = make(chan struct{})
+	cache := NewCachingProvider(backend, 10, time.Minute, time.Minute, time.Minute)
+
+	first, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error)
+
+	go func() {
+		_, err := cache.GetDecentralizedIDForHandle(first, testCacheAlice)
+		firstErr <- err
+	}()
+
+	assert.Eventually(t, func() bool { return backend.handleLookups.Load() == 1 }, time.Second, time.Millisecond)
+
+	type lookup struct {
+		did DecentralizedID
+		err error
+	}
+
+	second := make(chan lookup)
+
+	go func() {
+		did, err := cache.GetDecentralizedIDForHandle(context.Background(), testCacheAlice)
+		second <- lookup{did, err}
+	}()
+
+	cancel()
+	assert.ErrorIs(t, <-firstErr, context.Canceled)
+
+	close(backend.release)
+	result := <-second
+
+	assert.NoError(t, result.err)
+	assert.Equal(t, DecentralizedID("did:plc:example001"), result.did)
+	assert.Equal(t, int32(1), backend.handleLookups.Load())
+}
+
+func TestForgettingDomainForgetsItsHandles(t *testing.T) {
+	backend := newTestCountingProvider()
+	cache := NewCachingProvider(backend, 10, time.Minute, time.Minute, time.Minute)
+
+	_, _ = cache.CanProvideForDomain(context.Background(), "example.com")
+	_, _ = cache.GetDecentralizedIDForHandle(context.Background(), testCacheAlice)
+
+	cache.ForgetDomain("example.com")
+
+	assert.Equal(t, 0, cache.handles.len())
+	assert.Equal(t, 0, cache.domains.len())
+}
+
+func TestForgettingWildcardDomainForgetsDomainsBelowIt(t *testing.T) {
+	backend := newTestCountingProvider()
+	cache := NewCachingProvider(backend, 10, time.Minute, time.Minute, time.Minute)
+
+	_, _ = cache.CanProvideForDomain(context.Background(), "example.com")
+	_, _ = cache.CanProvideForDomain(context.Background(), "team.example.com")
+	_, _ = cache.CanProvideForDomain(context.Background(), "example.net")
+
+	cache.ForgetDomain("*.example.com")
+
+	assert.Equal(t, 1, cache.domains.len())
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// ConflictPolicy decides which Decentralized ID a chain of providers returns
+// when more than one provider has a Decentralized ID for a handle.
+type ConflictPolicy string
+
+const (
+	// ConflictFirst uses the first provider with a Decentralized ID.
+	ConflictFirst ConflictPolicy = "first"
+	// ConflictLast uses the last provider with a Decentralized ID.
+	ConflictLast ConflictPolicy = "last"
+	// ConflictError fails to resolve handles with different Decentralized IDs.
+	ConflictError ConflictPolicy = "error"
+)
+
+func (policy *ConflictPolicy) UnmarshalText(text []byte) error {
+	switch ConflictPolicy(text) {
+	case ConflictFirst, ConflictLast, ConflictError:
+		*policy = ConflictPolicy(text)
+		return nil
+	default:
+		return fmt.Errorf("%q is not first, last or error", text)
+	}
+}
+
+var ErrChainCannotChangeHandle = errors.New("handle cannot be changed through the chain")
+
+type ConflictingDecentralizedIDsError struct {
+	handle Handle
+	dids   []DecentralizedID
+}
+
+func (e ConflictingDecentralizedIDsError) Error() string {
+	dids := make([]string, len(e.dids))
+
+	for i, did := range e.dids {
+		dids[i] = string(did)
+	}
+
+	return fmt.Sprintf("Providers have conflicting DIDs for %s (%s)", e.handle, strings.Join(dids, ", "))
+}
+
+// UnwrapsProviders is implemented by providers which combine other providers.
+type UnwrapsProviders interface {
+	Unwrap() []ProvidesDecentralizedIDs
+}
+
+// ChainProvider queries providers in order, providing for the domains of all
+// of its providers. Changes are made by one of its providers.
+type ChainProvider struct {
+	names     []string
+	providers []ProvidesDecentralizedIDs
+	policy    ConflictPolicy
+	writes    int
+}
+
+// NewChainProvider makes changes with the provider named writes, or with the
+// last provider when writes is empty.
+func NewChainProvider(names []string, providers []ProvidesDecentralizedIDs, policy ConflictPolicy, writes string) *ChainProvider {
+	index := slices.Index(names, writes)
+
+	if index < 0 {
+		index = len(providers) - 1
+	}
+
+	return &ChainProvider{names, providers, policy, index}
+}
+
+func (chain *ChainProvider) GetDecentralizedIDForHandle(ctx context.Context, handle Handle) (DecentralizedID, error) {
+	var dids []DecentralizedID
+
+	canProvide := false
+
+	for i, provider := range chain.providers {
+		did, err := provider.GetDecentralizedIDForHandle(ctx, handle)
+
+		if errors.Is(err, (*CannotGetHandelsFromDomainError)(nil)) {
+			continue
+		}
+
+		if err != nil {
+			return "", fmt.Errorf("%s provider: %w", chain.names[i], err)
+		}
+
+		canProvide = true
+
+		if did == "" {
+			continue
+		}
+
+		if chain.policy == ConflictFirst {
+			return did, nil
+		}
+
+		if len(dids) > 0 && did != dids[0] && chain.policy == ConflictError {
+			return "", ConflictingDecentralizedIDsError{handle, append(dids, did)}
+		}
+
+		dids = append(dids, did)
+	}
+
+	if !canProvide {
+		return "", &CannotGetHandelsFromDomainError{domain: handle.Domain}
+	}
+
+	if len(dids) == 0 {
+		return "", nil
+	}
+
+	return dids[len(dids)-1], nil
+}
+
+func (chain *ChainProvider) CanProvideForDomain(ctx context.Context, domain Domain) (bool, error) {
+	var errs []error
+
+	for i, provider := range chain.providers {
+		canProvide, err := provider.CanProvideForDomain(ctx, domain)
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s provider: %w", chain.names[i], err))
+			continue
+		}
+
+		if canProvide {
+			return true, nil
+		}
+	}
+
+	return false, errors.Join(errs...)
+}
+
+// IsHealthy is only healthy when every provider is healthy.
+func (chain *ChainProvider) IsHealthy(ctx context.Context) (bool, string) {
+	healthy := true
+	statuses := make([]string, len(chain.providers))
+
+	for i, provider := range chain.providers {
+		providerHealthy, status := provider.IsHealthy(ctx)
+
+		healthy = healthy && providerHealthy
+		statuses[i] = fmt.Sprintf("%s: %s", chain.names[i], status)
+	}
+
+	return healthy, strings.Join(statuses, "; ")
+}
+
+// chainAs finds the provider with a capability, looking at the provider which
+// makes changes before the others in order, so that changes made through the
+// chain are the ones it reads.
+func chainAs[T any](chain *ChainProvider) (T, bool) {
+	capable, _, ok := chainIndexAs[T](chain)
+
+	return capable, ok
+}
+
+// chainIndexAs also finds the index of the provider with the capability.
+func chainIndexAs[T any](chain *ChainProvider) (T, int, bool) {
+	if capable, ok := ProviderAs[T](chain.providers[chain.writes]); ok {
+		return capable, chain.writes, true
+	}
+
+	for i, provider := range chain.providers {
+		if capable, ok := ProviderAs[T](provider); ok {
+			return capable, i, true
+		}
+	}
+
+	var none T
+
+	return none, -1, false
+}
+
+func (chain *ChainProvider) manager() (ManagesDecentralizedIDs, error) {
+	manager, ok := chainAs[ManagesDecentralizedIDs](chain)
+
+	if !ok {
+		return nil, ErrProviderIsReadOnly
+	}
+
+	return manager, nil
+}
+
+// handleManager finds the provider which changes a handle, refusing to change
+// a handle on a domain it does not provide for, or which another provider has
+// a Decentralized ID for, as the chain would not resolve the change.
+func (chain *ChainProvider) handleManager(ctx context.Context, handle Handle) (ManagesDecentralizedIDs, error) {
+	manager, writes, ok := chainIndexAs[ManagesDecentralizedIDs](chain)
+
+	if !ok {
+		return nil, ErrProviderIsReadOnly
+	}
+
+	canProvide, err := chain.providers[writes].CanProvideForDomain(ctx, handle.Domain)
+
+	if err != nil {
+		return nil, fmt.Errorf("%s provider: %w", chain.names[writes], err)
+	}
+
+	if !canProvide {
+		return nil, fmt.Errorf("%s is not a domain of the %s provider: %w", handle.Domain, chain.names[writes], ErrChainCannotChangeHandle)
+	}
+
+	for i, provider := range chain.providers {
+		if i == writes {
+			continue
+		}
+
+		did, err := provider.GetDecentralizedIDForHandle(ctx, handle)
+
+		if errors.Is(err, (*CannotGetHandelsFromDomainError)(nil)) {
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%s provider: %w", chain.names[i], err)
+		}
+
+		if did != "" {
+			return nil, fmt.Errorf("%s is provided by the %s provider, not the %s provider: %w", handle, chain.names[i], chain.names[writes], ErrChainCannotChangeHandle)
+		}
+	}
+
+	return manager, nil
+}
+
+func (chain *ChainProvider) ListDecentralizedIDs(ctx context.Context) (MapOfDids, error) {
+	manager, err := chain.manager()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return manager.ListDecentralizedIDs(ctx)
+}
+
+func (chain *ChainProvider) SetDecentralizedIDForHandle(ctx context.Context, handle Handle, did DecentralizedID) error {
+	manager, err := chain.handleManager(ctx, handle)
+
+	if err != nil {
+		return err
+	}
+
+	return manager.SetDecentralizedIDForHandle(ctx, handle, did)
+}
+
+func (chain *ChainProvider) DeleteDecentralizedIDForHandle(ctx context.Context, handle Handle) error {
+	manager, err := chain.handleManager(ctx, handle)
+
+	if err != nil {
+		return err
+	}
+
+	return manager.DeleteDecentralizedIDForHandle(ctx, handle)
+}
+
+func (chain *ChainProvider) ListDomains(ctx context.Context) ([]Domain, error) {
+	manager, err := chain.manager()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return manager.ListDomains(ctx)
+}
+
+func (chain *ChainProvider) AddDomain(ctx context.Context, domain Domain) error {
+	manager, err := chain.manager()
+
+	if err != nil {
+		return err
+	}
+
+	return manager.AddDomain(ctx, domain)
+}
+
+func (chain *ChainProvider) RemoveDomain(ctx context.Context, domain Domain) error {
+	manager, err := chain.manager()
+
+	if err != nil {
+		return err
+	}
+
+	return manager.RemoveDomain(ctx, domain)
+}
+
+func (chain *ChainProvider) GetDomainSettings(ctx context.Context, domain Domain) (DomainSettings, error) {
+	settings, ok := chainAs[ProvidesDomainSettings](chain)
+
+	if !ok {
+		return DomainSettings{}, nil
+	}
+
+	return settings.GetDomainSettings(ctx, domain)
+}
+
+func (chain *ChainProvider) SetDomainSettings(ctx context.Context, domain Domain, settings DomainSettings) error {
+	manager, ok := chainAs[ManagesDomainSettings](chain)
+
+	if !ok {
+		return ErrProviderIsReadOnly
+	}
+
+	return manager.SetDomainSettings(ctx, domain, settings)
+}
+
+func (chain *ChainProvider) GetDIDDocument(ctx context.Context, handle Handle) (*DIDDocument, error) {
+	documents, ok := chainAs[ProvidesDIDDocuments](chain)
+
+	if !ok {
+		return nil, nil
+	}
+
+	return documents.GetDIDDocument(ctx, handle)
+}
+
+func (chain *ChainProvider) SetDIDDocument(ctx context.Context, handle Handle, document DIDDocument) error {
+	manager, ok := chainAs[ManagesDIDDocuments](chain)
+
+	if !ok {
+		return ErrProviderIsReadOnly
+	}
+
+	return manager.SetDIDDocument(ctx, handle, document)
+}
+
+func (chain *ChainProvider) DeleteDIDDocument(ctx context.Context, handle Handle) error {
+	manager, ok := chainAs[ManagesDIDDocuments](chain)
+
+	if !ok {
+		return ErrProviderIsReadOnly
+	}
+
+	return manager.DeleteDIDDocument(ctx, handle)
+}
+
+func (chain *ChainProvider) Unwrap() []ProvidesDecentralizedIDs {
+	return chain.providers
+}
+
+func (chain *ChainProvider) Close() error {
+	var errs []error
+
+	for i, provider := range chain.providers {
+		if closer, ok := provider.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("%s provider: %w", chain.names[i], err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
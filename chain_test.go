@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestChainProvider(policy ConflictPolicy) (*ChainProvider, *InMemoryProvider, *InMemoryProvider) {
+	first := NewInMemoryProvider(MapOfDids{
+		"alice.example.com": "did:plc:example001",
+		"bob.example.com":   "did:plc:example002",
+	}, MapOfDomains{"example.com": true})
+
+	second := NewInMemoryProvider(MapOfDids{
+		"bob.example.com":   "did:plc:example003",
+		"carol.example.com": "did:plc:example004",
+		"dave.example.net":  "did:plc:example005",
+	}, MapOfDomains{"example.com": true, "example.net": true})
+
+	return NewChainProvider([]string{"first", "second"}, []ProvidesDecentralizedIDs{first, second}, policy, ""), first, second
+}
+
+func TestChainResolvesHandlesUsingConflictPolicy(t *testing.T) {
+	tests := []struct {
+		policy   ConflictPolicy
+		username Username
+		domain   Domain
+		expected DecentralizedID
+	}{
+		{ConflictFirst, "alice", "example.com", "did:plc:example001"},
+		{ConflictFirst, "bob", "example.com", "did:plc:example002"},
+		{ConflictFirst, "carol", "example.com", "did:plc:example004"},
+		{ConflictFirst, "dave", "example.net", "did:plc:example005"},
+		{ConflictFirst, "erin", "example.com", ""},
+		{ConflictLast, "bob", "example.com", "did:plc:example003"},
+		{ConflictLast, "alice", "example.com", "did:plc:example001"},
+		{ConflictError, "alice", "example.com", "did:plc:example001"},
+	}
+
+	for _, test := range tests {
+		chain, _, _ := newTestChainProvider(test.policy)
+
+		did, err := chain.GetDecentralizedIDForHandle(context.Background(), Handle{Domain: test.domain, Username: test.username})
+
+		assert.NoError(t, err, "%s %s", test.policy, test.username)
+		assert.Equal(t, test.expected, did, "%s %s", test.policy, test.username)
+	}
+}
+
+func TestChainReportsConflictingDecentralizedIDs(t *testing.T) {
+	chain, _, _ := newTestChainProvider(ConflictError)
+
+	_, err := chain.GetDecentralizedIDForHandle(context.Background(), Handle{Domain: "example.com", Username: "bob"})
+
+	assert.ErrorAs(t, err, &ConflictingDecentralizedIDsError{})
+}
+
+func TestChainProvidesForDomainsOfAllProviders(t *testing.T) {
+	chain, _, _ := newTestChainProvider(ConflictFirst)
+
+	for domain, expected := range map[Domain]bool{"example.com": true, "example.net": true, "example.org": false} {
+		canProvide, err := chain.CanProvideForDomain(context.Background(), domain)
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected, canProvide, domain)
+	}
+
+	_, err := chain.GetDecentralizedIDForHandle(context.Background(), Handle{Domain: "example.org", Username: "alice"})
+
+	assert.ErrorIs(t, err, (*CannotGetHandelsFromDomainError)(nil))
+}
+
+func TestChainIsOnlyHealthyWhenAllProvidersAreHealthy(t *testing.T) {
+	chain, _, second := newTestChainProvider(ConflictFirst)
+
+	healthy, _ := chain.IsHealthy(context.Background())
+	assert.True(t, healthy)
+
+	second.SetHealthy(false)
+
+	healthy, status := chain.IsHealthy(context.Background())
+	assert.False(t, healthy)
+	assert.Equal(t, "first: Available with 2 handles for 1 domains; second: Not healthy", status)
+}
+
+func TestProviderAsSearchesChainInOrder(t *testing.T) {
+	chain, first, _ := newTestChainProvider(ConflictFirst)
+
+	memory, ok := ProviderAs[*InMemoryProvider](NewCachingProvider(chain, 10, 0, 0, 0))
+
+	assert.True(t, ok)
+	assert.Same(t, first, memory)
+}
+
+func TestChainMakesChangesWithItsLastProvider(t *testing.T) {
+	chain, first, second := newTestChainProvider(ConflictFirst)
+	ctx := context.Background()
+
+	assert.NoError(t, chain.SetDecentralizedIDForHandle(ctx, Handle{Domain: "example.com", Username: "erin"}, "did:plc:example006"))
+	assert.NoError(t, chain.SetDomainSettings(ctx, "example.com", DomainSettings{RedirectStatus: 308}))
+
+	dids, _ := first.ListDecentralizedIDs(ctx)
+	assert.NotContains(t, dids, Hostname("erin.example.com"))
+
+	dids, _ = second.ListDecentralizedIDs(ctx)
+	assert.Equal(t, DecentralizedID("did:plc:example006"), dids["erin.example.com"])
+
+	settings, _ := chain.GetDomainSettings(ctx, "example.com")
+	assert.Equal(t, 308, settings.RedirectStatus)
+}
+
+func TestChainMakesChangesWithConfiguredProvider(t *testing.T) {
+	t.Setenv("DID_PROVIDER", "chain:memory,sqlite")
+	t.Setenv("CHAIN_WRITE_PROVIDER", "memory")
+	t.Setenv("MEMORY_DIDS", "alice.example.com@did:plc:example001")
+	t.Setenv("MEMORY_DOMAINS", "example.com")
+	t.Setenv("SQLITE_PATH", filepath.Join(t.TempDir(), "handles.db"))
+
+	config, err := ConfigFromEnvironment()
+
+	assert.NoError(t, err)
+	defer config.Provider.(io.Closer).Close()
+
+	manager, _ := ProviderAs[ManagesDecentralizedIDs](config.Provider)
+	assert.NoError(t, manager.SetDecentralizedIDForHandle(context.Background(), Handle{Domain: "example.com", Username: "bob"}, "did:plc:example002"))
+
+	memory, _ := ProviderAs[*InMemoryProvider](config.Provider)
+	dids, _ := memory.ListDecentralizedIDs(context.Background())
+	assert.Equal(t, DecentralizedID("did:plc:example002"), dids["bob.example.com"])
+
+	t.Setenv("CHAIN_WRITE_PROVIDER", "postgres")
+
+	_, err = ConfigFromEnvironment()
+	assert.Error(t, err)
+}
+
+func TestAdminCannotChangeHandlesOfOtherProvidersInChain(t *testing.T) {
+	t.Setenv("DID_PROVIDER", "chain:memory,sqlite")
+	t.Setenv("MEMORY_DIDS", "alice.example.com@did:plc:example001")
+	t.Setenv("MEMORY_DOMAINS", "example.com")
+	t.Setenv("SQLITE_PATH", filepath.Join(t.TempDir(), "handles.db"))
+
+	config, err := ConfigFromEnvironment()
+
+	assert.NoError(t, err)
+	defer config.Provider.(io.Closer).Close()
+
+	router := NewTestAdminEnvironment(config.Provider)
+
+	res := adminRequest(router, "DELETE", "/admin/handles/alice.example.com", "")
+	assert.Equal(t, http.StatusConflict, res.Code)
+
+	res = adminRequest(router, "PUT", "/admin/handles/alice.example.com", `{"did": "did:plc:example002"}`)
+	assert.Equal(t, http.StatusConflict, res.Code)
+
+	res = adminRequest(router, "GET", "/admin/handles/alice.example.com", "")
+	assert.JSONEq(t, `{"handle": "alice.example.com", "did": "did:plc:example001"}`, res.Body.String())
+
+	res = adminRequest(router, "POST", "/admin/handles", `{"handle": "bob.example.com", "did": "did:plc:example002"}`)
+	assert.Equal(t, http.StatusConflict, res.Code)
+
+	res = adminRequest(router, "PUT", "/admin/domains/example.com", "")
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	res = adminRequest(router, "POST", "/admin/handles", `{"handle": "bob.example.com", "did": "did:plc:example002"}`)
+	assert.Equal(t, http.StatusCreated, res.Code)
+
+	res = adminRequest(router, "DELETE", "/admin/handles/bob.example.com", "")
+	assert.Equal(t, http.StatusNoContent, res.Code)
+
+	res = adminRequest(router, "GET", "/admin/handles/bob.example.com", "")
+	assert.Equal(t, http.StatusNotFound, res.Code)
+}
+
+func TestProvidersAsFindsEveryProviderInChain(t *testing.T) {
+	chain, first, second := newTestChainProvider(ConflictFirst)
+
+	memories := ProvidersAs[*InMemoryProvider](NewCachingProvider(chain, 10, 0, 0, 0))
+
+	assert.Equal(t, []*InMemoryProvider{first, second}, memories)
+	assert.Empty(t, ProvidersAs[*PostgresHandles](chain))
+}
+
+func TestChainIsConfiguredFromEnvironment(t *testing.T) {
+	t.Setenv("DID_PROVIDER", "chain:memory,sqlite")
+	t.Setenv("CHAIN_CONFLICT_POLICY", "last")
+	t.Setenv("MEMORY_DIDS", "alice.example.com@did:plc:example001")
+	t.Setenv("MEMORY_DOMAINS", "example.com")
+	t.Setenv("SQLITE_PATH", filepath.Join(t.TempDir(), "handles.db"))
+
+	config, err := ConfigFromEnvironment()
+
+	assert.NoError(t, err)
+	defer config.Provider.(io.Closer).Close()
+
+	assert.Equal(t, "DIDWebProvider -> ChainProvider(InMemoryProvider, SQLiteHandles)", describeProvider(config.Provider))
+
+	chain, _ := ProviderAs[*ChainProvider](config.Provider)
+	assert.Equal(t, ConflictLast, chain.policy)
+}
+
+func TestInvalidChainIsRejected(t *testing.T) {
+	for _, provider := range []string{"chain:", "chain:memory,memory", "chain:memory,", "chain:memory,unknown", "chain:chain:memory"} {
+		t.Run(provider, func(t *testing.T) {
+			t.Setenv("DID_PROVIDER", provider)
+			t.Setenv("MEMORY_DIDS", "alice.example.com@did:plc:example001")
+			t.Setenv("MEMORY_DOMAINS", "example.com")
+
+			_, err := ConfigFromEnvironment()
+
+			assert.Error(t, err)
+		})
+	}
+}
@@ -1,18 +1,31 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"net"
+	"net/http"
 	"os"
 	"reflect"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/caarlos0/env/v11"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/multitracer"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/jackc/pgx/v5/tracelog"
 	pgxslog "github.com/mcosta74/pgx-slog"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 type Config struct {
+	ConfigFile string `env:"CONFIG_FILE"`
+
 	Host string `env:"HOST" envDefault:"localhost"`
 	Port string `env:"PORT" envDefault:"8080"`
 
@@ -21,22 +34,169 @@ type Config struct {
 	RedirectDIDTemplate    URLTemplate `env:"REDIRECT_DID_TEMPLATE" envDefault:"https://bsky.app/profile/{did}"`
 	RedirectHandleTemplate URLTemplate `env:"REDIRECT_HANDLE_TEMPLATE" envDefault:"https://{handle.domain}?handle={handle}"`
 
-	Postgres             *pgxpool.Config `env:"DATABASE_URL"`
-	PostgresDidsTable    string          `env:"DATABASE_TABLE_DIDS" envDefault:"dids"`
-	PostgresDomainsTable string          `env:"DATABASE_TABLE_DOMAINS" envDefault:"domains"`
+	RedirectDIDStatus    int            `env:"REDIRECT_DID_STATUS" envDefault:"307"`
+	RedirectHandleStatus int            `env:"REDIRECT_HANDLE_STATUS" envDefault:"307"`
+	NotFoundPage         bool           `env:"NOT_FOUND_PAGE" envDefault:"false"`
+	RedirectHead         MethodResponse `env:"REDIRECT_HEAD" envDefault:"redirect"`
+	RedirectOtherMethods MethodResponse `env:"REDIRECT_OTHER_METHODS" envDefault:"redirect"`
+
+	TracingEnabled bool                     `env:"TRACING_ENABLED" envDefault:"false"`
+	TracerProvider *sdktrace.TracerProvider `env:"-"`
+
+	Postgres               *pgxpool.Config `env:"DATABASE_URL"`
+	PostgresDidsTable      string          `env:"DATABASE_TABLE_DIDS" envDefault:"dids"`
+	PostgresDomainsTable   string          `env:"DATABASE_TABLE_DOMAINS" envDefault:"domains"`
+	PostgresDocumentsTable string          `env:"DATABASE_TABLE_DOCUMENTS"`
+	PostgresNotifyChannel  string          `env:"DATABASE_NOTIFY_CHANNEL"`
+
+	SQLitePath string `env:"SQLITE_PATH"`
 
 	MemoryDids    map[string]string `env:"MEMORY_DIDS" envKeyValSeparator:"@"`
 	MemoryDomains []string          `env:"MEMORY_DOMAINS"`
 
+	MemoryDomainSettings MapOfDomainSettings `env:"MEMORY_DOMAIN_SETTINGS"`
+	MemoryDIDDocuments   MapOfDIDDocuments   `env:"MEMORY_DID_DOCUMENTS"`
+
+	FilePath string `env:"FILE_PATH"`
+
+	SheetsDidsURL         string        `env:"SHEETS_DIDS_URL"`
+	SheetsDomainsURL      string        `env:"SHEETS_DOMAINS_URL"`
+	SheetsRefreshInterval time.Duration `env:"SHEETS_REFRESH_INTERVAL" envDefault:"5m"`
+	SheetsRequestTimeout  time.Duration `env:"SHEETS_REQUEST_TIMEOUT" envDefault:"10s"`
+
+	UpstreamURL             URLTemplate   `env:"UPSTREAM_URL"`
+	UpstreamHost            URLTemplate   `env:"UPSTREAM_HOST"`
+	UpstreamAPI             UpstreamAPI   `env:"UPSTREAM_API" envDefault:"well-known"`
+	UpstreamDomains         []string      `env:"UPSTREAM_DOMAINS"`
+	UpstreamTimeout         time.Duration `env:"UPSTREAM_TIMEOUT" envDefault:"5s"`
+	UpstreamRetries         int           `env:"UPSTREAM_RETRIES" envDefault:"2"`
+	UpstreamRetryDelay      time.Duration `env:"UPSTREAM_RETRY_DELAY" envDefault:"200ms"`
+	UpstreamBreakerFailures int           `env:"UPSTREAM_BREAKER_FAILURES" envDefault:"5"`
+	UpstreamBreakerCooldown time.Duration `env:"UPSTREAM_BREAKER_COOLDOWN" envDefault:"30s"`
+
+	ChainConflictPolicy ConflictPolicy `env:"CHAIN_CONFLICT_POLICY" envDefault:"first"`
+	ChainWriteProvider  string         `env:"CHAIN_WRITE_PROVIDER"`
+
+	DomainRoutes map[string]string `env:"DOMAIN_ROUTES"`
+
 	Provider ProvidesDecentralizedIDs `env:"DID_PROVIDER,required"`
 
+	CacheTTL               time.Duration `env:"CACHE_TTL" envDefault:"0s"`
+	CacheHandleNotFoundTTL time.Duration `env:"CACHE_HANDLE_NOT_FOUND_TTL" envDefault:"1m"`
+	CacheDomainNotFoundTTL time.Duration `env:"CACHE_DOMAIN_NOT_FOUND_TTL" envDefault:"5m"`
+	CacheSize              int           `env:"CACHE_SIZE" envDefault:"10000"`
+
 	CheckDomainParameter string `env:"CHECK_DOMAIN_PARAMETER" envDefault:"handle"`
+
+	AdminToken string `env:"ADMIN_TOKEN"`
+
+	VerifyHandles   bool            `env:"VERIFY_HANDLES" envDefault:"false"`
+	VerifyInterval  time.Duration   `env:"VERIFY_INTERVAL" envDefault:"1h"`
+	VerifySize      int             `env:"VERIFY_SIZE" envDefault:"10000"`
+	PLCDirectoryURL string          `env:"PLC_DIRECTORY_URL" envDefault:"https://plc.directory"`
+	Verifier        *HandleVerifier `env:"-"`
+
+	MetricsEnabled bool     `env:"METRICS_ENABLED" envDefault:"false"`
+	Metrics        *Metrics `env:"-"`
+
+	DNSAddress       string        `env:"DNS_ADDRESS"`
+	DNSTTL           time.Duration `env:"DNS_TTL" envDefault:"5m"`
+	DNSHostAddresses []net.IP      `env:"DNS_HOST_ADDRESSES"`
+	DNSNameservers   []string      `env:"DNS_NAMESERVERS"`
+
+	TLSAddress          string `env:"TLS_ADDRESS"`
+	TLSCertificatesPath string `env:"TLS_CERTIFICATES_PATH"`
+
+	ACMEEnabled      bool   `env:"ACME_ENABLED" envDefault:"false"`
+	ACMEDirectoryURL string `env:"ACME_DIRECTORY_URL" envDefault:"https://acme-v02.api.letsencrypt.org/directory"`
+	ACMEEmail        string `env:"ACME_EMAIL"`
+	ACMECachePath    string `env:"ACME_CACHE_PATH"`
+	ACMECacheTable   string `env:"ACME_CACHE_TABLE"`
+
+	Reload func() error `env:"-"`
+
+	ShutdownDelay   time.Duration `env:"SHUTDOWN_DELAY" envDefault:"0s"`
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" envDefault:"30s"`
+	Lifecycle       *Lifecycle    `env:"-"`
+
+	// environment is read by the providers of the routes provider, which are
+	// configured by variables prefixed with `PROVIDERS_<NAME>_`.
+	environment map[string]string
+	// instance names the routed provider being configured.
+	instance string
 }
 
 func ConfigFromEnvironment() (Config, error) {
-	config := Config{}
+	return configFromEnvironment(nil)
+}
+
+// ReloadConfigFromEnvironment reads the configuration again, keeping the
+// lifecycle, metrics and tracing of the running configuration.
+func ReloadConfigFromEnvironment(running Config) (Config, error) {
+	return configFromEnvironment(&running)
+}
+
+func configFromEnvironment(running *Config) (Config, error) {
+	config := Config{Lifecycle: &Lifecycle{}}
+
+	if running != nil {
+		config.Lifecycle = running.Lifecycle
+	}
+
+	environment := env.ToMap(os.Environ())
+
+	if path := environment["CONFIG_FILE"]; path != "" {
+		settings, err := ReadConfigFile(path)
+
+		if err != nil {
+			return Config{}, err
+		}
+
+		for name, value := range settings {
+			if _, ok := environment[name]; !ok {
+				environment[name] = value
+			}
+		}
+	}
+
+	// Variables which are not valid do not stop the provider being created.
+	if err := parseConfig(&config, environment); err != nil {
+		if closer, ok := config.Provider.(io.Closer); ok {
+			closer.Close()
+		}
+
+		return Config{}, err
+	}
+
+	if err := config.prepare(running); err != nil {
+		if closer, ok := config.Provider.(io.Closer); ok {
+			closer.Close()
+		}
+
+		return Config{}, err
+	}
+
+	return config, nil
+}
+
+func (config Config) Redirects() Redirects {
+	return Redirects{
+		DIDTemplate:    config.RedirectDIDTemplate,
+		HandleTemplate: config.RedirectHandleTemplate,
+		DIDStatus:      config.RedirectDIDStatus,
+		HandleStatus:   config.RedirectHandleStatus,
+		NotFoundPage:   config.NotFoundPage,
+		Head:           config.RedirectHead,
+		OtherMethods:   config.RedirectOtherMethods,
+	}
+}
+
+// parseConfig reads a configuration from environment variables.
+func parseConfig(config *Config, environment map[string]string) error {
+	config.environment = environment
 
-	err := env.ParseWithOptions(&config, env.Options{
+	return env.ParseWithOptions(config, env.Options{
+		Environment: environment,
 		FuncMap: map[reflect.Type]env.ParserFunc{
 			reflect.TypeFor[slog.Logger](): func(v string) (interface{}, error) {
 				var level slog.Level
@@ -54,54 +214,429 @@ func ConfigFromEnvironment() (Config, error) {
 			reflect.TypeFor[pgxpool.Config](): func(v string) (interface{}, error) {
 				databaseConfig, err := pgxpool.ParseConfig(v)
 
-				databaseConfig.ConnConfig.Tracer = &tracelog.TraceLog{
+				if err != nil {
+					return nil, err
+				}
+
+				var tracer pgx.QueryTracer = &tracelog.TraceLog{
 					Logger:   pgxslog.NewLogger(config.Logger),
 					LogLevel: tracelog.LogLevelDebug,
 				}
 
-				return *databaseConfig, err
+				if config.TracingEnabled {
+					tracer = multitracer.New(tracer, PostgresTracer{})
+				}
+
+				databaseConfig.ConnConfig.Tracer = tracer
+
+				return *databaseConfig, nil
 			},
 			reflect.TypeFor[ProvidesDecentralizedIDs](): func(v string) (interface{}, error) {
-				switch v {
-				case "postgres":
-					if config.Postgres == nil {
-						return &PostgresHandles{}, errors.New("a database connection (`DATABASE_URL`) is required to use the postgres provider")
-					}
-
-					return NewPostgresHandlesProvider(
-						config.Postgres,
-						config.PostgresDidsTable,
-						config.PostgresDomainsTable,
-					)
-				case "memory":
-					if config.MemoryDids == nil || config.MemoryDomains == nil {
-						return nil, errors.New("a map of Decentralized IDs (`MEMORY_DIDS`) and domains (`MEMORY_DOMAINS`) is required to use the memory provider")
-					}
-
-					dids := make(MapOfDids)
-
-					for handle, did := range config.MemoryDids {
-						dids[Hostname(handle)] = DecentralizedID(did)
-					}
-
-					domains := make(MapOfDomains)
-
-					for _, domain := range config.MemoryDomains {
-						domains[Domain(domain)] = true
-					}
-
-					provider := NewInMemoryProvider(dids, domains)
-					return provider, nil
-				default:
-					return nil, errors.New("no valid provider of decentralized IDs specified")
-				}
+				return config.newProvider(v)
 			},
 		},
 	})
+}
+
+// prepare checks settings which depend on each other and decorates the
+// provider with the features which are enabled.
+func (config *Config) prepare(running *Config) error {
+	if err := ValidateRedirectStatus(config.RedirectDIDStatus); err != nil {
+		return fmt.Errorf("`REDIRECT_DID_STATUS` is not valid: %w", err)
+	}
+
+	if err := ValidateRedirectStatus(config.RedirectHandleStatus); err != nil {
+		return fmt.Errorf("`REDIRECT_HANDLE_STATUS` is not valid: %w", err)
+	}
+
+	if config.TLSAddress != "" && config.TLSCertificatesPath == "" && !config.ACMEEnabled {
+		return errors.New("a directory of certificates (`TLS_CERTIFICATES_PATH`) or ACME (`ACME_ENABLED`) is required to listen for HTTPS (`TLS_ADDRESS`)")
+	}
+
+	// The directory is loaded again, and watched for changes, when listening.
+	if config.TLSAddress != "" && config.TLSCertificatesPath != "" {
+		if _, err := LoadCertificateDirectory(config.TLSCertificatesPath); err != nil {
+			return fmt.Errorf("`TLS_CERTIFICATES_PATH` is not valid: %w", err)
+		}
+	}
+
+	if config.DNSAddress != "" && len(config.DNSNameservers) == 0 {
+		return errors.New("the hostnames of the nameservers (`DNS_NAMESERVERS`) are required to answer DNS queries (`DNS_ADDRESS`)")
+	}
+
+	for _, nameserver := range config.DNSNameservers {
+		if err := ValidateHostname(strings.ToLower(nameserver)); err != nil {
+			return fmt.Errorf("nameserver %s (`DNS_NAMESERVERS`) is not valid: %w", nameserver, err)
+		}
+	}
+
+	if config.ACMEEnabled && config.TLSAddress == "" {
+		return errors.New("an address to listen for HTTPS (`TLS_ADDRESS`) is required to issue certificates using ACME (`ACME_ENABLED`)")
+	}
+
+	if config.ACMEEnabled && config.ACMECachePath == "" && config.ACMECacheTable == "" {
+		return errors.New("a directory (`ACME_CACHE_PATH`) or table (`ACME_CACHE_TABLE`) is required to store certificates issued using ACME (`ACME_ENABLED`)")
+	}
+
+	if config.ACMEEnabled && config.ACMECachePath == "" {
+		if _, ok := ProviderAs[*PostgresHandles](config.Provider); !ok {
+			return errors.New("the postgres provider is required to store certificates in a table (`ACME_CACHE_TABLE`)")
+		}
+	}
+
+	if documents, ok := ProviderAs[ProvidesDIDDocuments](config.Provider); ok {
+		config.Provider = NewDIDWebProvider(config.Provider, documents)
+	}
+
+	if config.CacheTTL > 0 {
+		cache := NewCachingProvider(
+			config.Provider,
+			config.CacheSize,
+			config.CacheTTL,
+			config.CacheHandleNotFoundTTL,
+			config.CacheDomainNotFoundTTL,
+		)
+
+		if config.PostgresNotifyChannel != "" {
+			for _, pg := range ProvidersAs[*PostgresHandles](config.Provider) {
+				go pg.ListenForChanges(context.Background(), config.PostgresNotifyChannel, cache, config.Logger)
+			}
+		}
+
+		config.Provider = cache
+	} else if config.PostgresNotifyChannel != "" {
+		return errors.New("a cache (`CACHE_TTL`) is required to listen for changes (`DATABASE_NOTIFY_CHANNEL`)")
+	}
+
+	if config.VerifyHandles {
+		if config.VerifyInterval <= 0 || config.VerifySize <= 0 {
+			return errors.New("an interval (`VERIFY_INTERVAL`) and a number of results (`VERIFY_SIZE`) to remember are required to verify handles (`VERIFY_HANDLES`)")
+		}
+
+		config.Verifier = NewHandleVerifier(
+			NewDIDDocumentResolver(config.PLCDirectoryURL, &http.Client{Timeout: 10 * time.Second}),
+			config.VerifyInterval,
+			config.VerifySize,
+			config.Logger,
+		)
+
+		config.Provider = NewVerifyingProvider(config.Provider, config.Verifier)
+	}
+
+	if config.MetricsEnabled {
+		if running != nil && running.Metrics != nil {
+			config.Metrics = running.Metrics
+		} else {
+			config.Metrics = NewMetrics()
+		}
+
+		config.Provider = config.Metrics.MeasureProvider(config.Provider)
+	}
+
+	if config.TracingEnabled {
+		if running != nil && running.TracerProvider != nil {
+			config.TracerProvider = running.TracerProvider
+		} else {
+			tracerProvider, err := NewTracerProvider(context.Background())
+
+			if err != nil {
+				return err
+			}
+
+			config.TracerProvider = tracerProvider
+		}
+
+		config.Provider = NewTracedProvider(config.Provider)
+	}
+
+	return nil
+}
+
+func (config *Config) newProvider(name string) (ProvidesDecentralizedIDs, error) {
+	if names, ok := strings.CutPrefix(name, "chain:"); ok {
+		return config.newChainProvider(names)
+	}
+
+	switch name {
+	case "routes":
+		return config.newRoutingProvider()
+	case "postgres":
+		// A `DATABASE_URL` which is not valid leaves an empty configuration.
+		if config.Postgres == nil || config.Postgres.ConnConfig == nil {
+			return &PostgresHandles{}, errors.New("a database connection (`DATABASE_URL`) is required to use the postgres provider")
+		}
+
+		return NewPostgresHandlesProvider(
+			config.Postgres,
+			config.PostgresDidsTable,
+			config.PostgresDomainsTable,
+			config.PostgresDocumentsTable,
+		)
+	case "sqlite":
+		if config.SQLitePath == "" {
+			return nil, errors.New("a path to a database file (`SQLITE_PATH`) is required to use the sqlite provider")
+		}
+
+		return NewSQLiteHandlesProvider(
+			config.SQLitePath,
+			config.PostgresDidsTable,
+			config.PostgresDomainsTable,
+		)
+	case "memory":
+		if config.MemoryDids == nil || config.MemoryDomains == nil {
+			return nil, errors.New("a map of Decentralized IDs (`MEMORY_DIDS`) and domains (`MEMORY_DOMAINS`) is required to use the memory provider")
+		}
+
+		dids := make(MapOfDids)
+
+		for hostname, did := range config.MemoryDids {
+			handle, err := HostnameToHandle(hostname)
+
+			if err != nil {
+				return nil, fmt.Errorf("`MEMORY_DIDS` contains an invalid handle: %w", err)
+			}
+
+			if err := ValidateDecentralizedID(DecentralizedID(did)); err != nil {
+				return nil, fmt.Errorf("`MEMORY_DIDS` contains an invalid Decentralized ID: %w", err)
+			}
+
+			dids[Hostname(handle.String())] = DecentralizedID(did)
+		}
+
+		domains := make(MapOfDomains)
+
+		for _, domain := range config.MemoryDomains {
+			if err := ValidateDomain(Domain(strings.ToLower(domain))); err != nil {
+				return nil, fmt.Errorf("`MEMORY_DOMAINS` contains an invalid domain: %w", err)
+			}
+
+			domains[Domain(strings.ToLower(domain))] = true
+		}
+
+		provider := NewInMemoryProvider(dids, domains)
+
+		for domain, settings := range config.MemoryDomainSettings {
+			domain := Domain(strings.ToLower(string(domain)))
+
+			if !domains[domain] {
+				return nil, fmt.Errorf("`MEMORY_DOMAIN_SETTINGS` contains settings for %s which is not in `MEMORY_DOMAINS`", domain)
+			}
+
+			if err := settings.Validate(); err != nil {
+				return nil, fmt.Errorf("`MEMORY_DOMAIN_SETTINGS` contains invalid settings for %s: %w", domain, err)
+			}
+
+			_ = provider.SetDomainSettings(context.Background(), domain, settings)
+		}
+
+		for hostname, document := range config.MemoryDIDDocuments {
+			handle, err := HostnameToHandle(string(hostname))
+
+			if err != nil {
+				return nil, fmt.Errorf("`MEMORY_DID_DOCUMENTS` contains an invalid handle: %w", err)
+			}
+
+			handle, _ = HandleOnProvidedDomain(context.Background(), provider, handle)
+
+			if canProvide, _ := provider.CanProvideForDomain(context.Background(), handle.Domain); !canProvide {
+				return nil, fmt.Errorf("`MEMORY_DID_DOCUMENTS` contains a document for %s which is not in `MEMORY_DOMAINS`", handle)
+			}
+
+			if err := ValidateDIDDocument(handle, document); err != nil {
+				return nil, fmt.Errorf("`MEMORY_DID_DOCUMENTS` contains an invalid document for %s: %w", handle, err)
+			}
+
+			_ = provider.SetDIDDocument(context.Background(), handle, document)
+		}
+
+		return provider, nil
+	case "file":
+		if config.FilePath == "" {
+			return nil, errors.New("a path to a JSON, YAML or CSV file of handles (`FILE_PATH`) is required to use the file provider")
+		}
+
+		return NewFileProvider(config.FilePath, config.Logger)
+	case "sheets":
+		if config.SheetsDidsURL == "" || config.SheetsDomainsURL == "" {
+			return nil, errors.New("CSV URLs for a sheet of handles (`SHEETS_DIDS_URL`) and a sheet of domains (`SHEETS_DOMAINS_URL`) are required to use the sheets provider")
+		}
+
+		return NewSheetsProvider(
+			config.SheetsDidsURL,
+			config.SheetsDomainsURL,
+			config.SheetsRefreshInterval,
+			&http.Client{Timeout: config.SheetsRequestTimeout},
+			config.Logger,
+		)
+	case "upstream":
+		if config.UpstreamURL == "" {
+			return nil, errors.New("a URL template of another server (`UPSTREAM_URL`) is required to use the upstream provider")
+		}
+
+		for name, template := range map[string]URLTemplate{"UPSTREAM_URL": config.UpstreamURL, "UPSTREAM_HOST": config.UpstreamHost} {
+			if err := template.ValidateHandleTokens(); err != nil {
+				return nil, fmt.Errorf("`%s` is not valid: %w", name, err)
+			}
+		}
+
+		if !strings.HasPrefix(string(config.UpstreamURL), "https://") && !strings.HasPrefix(string(config.UpstreamURL), "http://") {
+			return nil, errors.New("`UPSTREAM_URL` must be an http or https URL")
+		}
+
+		if config.UpstreamRetries < 0 || config.UpstreamBreakerFailures < 0 {
+			return nil, errors.New("`UPSTREAM_RETRIES` and `UPSTREAM_BREAKER_FAILURES` cannot be negative")
+		}
+
+		var domains MapOfDomains
+
+		if config.UpstreamDomains != nil {
+			domains = make(MapOfDomains)
+
+			for _, domain := range config.UpstreamDomains {
+				if err := ValidateDomain(Domain(strings.ToLower(domain))); err != nil {
+					return nil, fmt.Errorf("`UPSTREAM_DOMAINS` contains an invalid domain: %w", err)
+				}
+
+				domains[Domain(strings.ToLower(domain))] = true
+			}
+		}
+
+		return NewUpstreamProvider(
+			config.UpstreamURL,
+			config.UpstreamHost,
+			config.UpstreamAPI,
+			domains,
+			&http.Client{Timeout: config.UpstreamTimeout},
+			config.UpstreamRetries,
+			config.UpstreamRetryDelay,
+			NewCircuitBreaker(config.UpstreamBreakerFailures, config.UpstreamBreakerCooldown),
+		), nil
+	default:
+		return nil, errors.New("no valid provider of decentralized IDs specified")
+	}
+}
+
+// newChainProvider creates each of the comma separated providers of
+// `chain:<provider>,<provider>`, closing them if any cannot be created.
+func (config *Config) newChainProvider(list string) (ProvidesDecentralizedIDs, error) {
+	names := strings.Split(list, ",")
+	providers := make([]ProvidesDecentralizedIDs, 0, len(names))
+
+	if config.ChainWriteProvider != "" && !slices.Contains(names, config.ChainWriteProvider) {
+		return nil, fmt.Errorf("chain %q does not have the %s provider to make changes with (`CHAIN_WRITE_PROVIDER`)", list, config.ChainWriteProvider)
+	}
+
+	for i, name := range names {
+		var provider ProvidesDecentralizedIDs
+		var err error
+
+		switch {
+		case name == "" || strings.HasPrefix(name, "chain:"):
+			err = fmt.Errorf("chain %q must be a comma separated list of providers", list)
+		case slices.Contains(names[:i], name):
+			err = fmt.Errorf("chain %q has the %s provider more than once", list, name)
+		default:
+			provider, err = config.newProvider(name)
+		}
+
+		if err != nil {
+			_ = NewChainProvider(names[:i], providers, config.ChainConflictPolicy, "").Close()
+			return nil, err
+		}
+
+		providers = append(providers, provider)
+	}
+
+	return NewChainProvider(names, providers, config.ChainConflictPolicy, config.ChainWriteProvider), nil
+}
+
+// newRoutingProvider creates the providers named by `DOMAIN_ROUTES`, each
+// configured by the variables prefixed with `PROVIDERS_<NAME>_`, closing them
+// if any cannot be created.
+func (config *Config) newRoutingProvider() (ProvidesDecentralizedIDs, error) {
+	if config.instance != "" {
+		return nil, fmt.Errorf("the %s provider cannot be a routes provider", config.instance)
+	}
+
+	routes, err := ParseDomainRoutes(config.DomainRoutes)
 
 	if err != nil {
-		return Config{}, err
+		return nil, fmt.Errorf("`DOMAIN_ROUTES` is not valid: %w", err)
 	}
 
-	return config, nil
+	providers := make(map[string]ProvidesDecentralizedIDs)
+
+	for _, route := range routes {
+		if _, ok := providers[route.Name]; ok {
+			continue
+		}
+
+		provider, err := config.newRoutedProvider(route.Name)
+
+		if err != nil {
+			_ = NewRoutingProvider(routes, providers).Close()
+			return nil, err
+		}
+
+		providers[route.Name] = provider
+	}
+
+	return NewRoutingProvider(routes, providers), nil
+}
+
+// routedProviderSettings are the prefixes of the only variables a routed
+// provider uses, since caching, verification and the servers are configured
+// once for every route.
+var routedProviderSettings = []string{
+	"DID_PROVIDER", "DATABASE_URL", "DATABASE_TABLE_", "SQLITE_", "MEMORY_",
+	"FILE_", "SHEETS_", "UPSTREAM_", "CHAIN_",
+}
+
+// newRoutedProvider configures a provider from the variables prefixed with
+// `PROVIDERS_<NAME>_`, which share the log level and tracing of the server.
+func (config *Config) newRoutedProvider(name string) (ProvidesDecentralizedIDs, error) {
+	prefix := "PROVIDERS_" + strings.ToUpper(name) + "_"
+	environment := make(map[string]string)
+
+	for _, inherited := range []string{"LOG_LEVEL", "TRACING_ENABLED"} {
+		if value, ok := config.environment[inherited]; ok {
+			environment[inherited] = value
+		}
+	}
+
+	var ignored []string
+
+	for key, value := range config.environment {
+		if setting, ok := strings.CutPrefix(key, prefix); ok {
+			if !slices.ContainsFunc(routedProviderSettings, func(allowed string) bool {
+				return strings.HasPrefix(setting, allowed)
+			}) {
+				ignored = append(ignored, "`"+key+"`")
+				continue
+			}
+
+			environment[setting] = value
+		}
+	}
+
+	if len(ignored) > 0 {
+		slices.Sort(ignored)
+		return nil, fmt.Errorf("%s are not used by the %s provider, set them without the prefix to apply them to every route", strings.Join(ignored, ", "), name)
+	}
+
+	if environment["DID_PROVIDER"] == "" {
+		return nil, fmt.Errorf("`%sDID_PROVIDER` is required to route domains to the %s provider", prefix, name)
+	}
+
+	instance := Config{instance: name}
+
+	if err := parseConfig(&instance, environment); err != nil {
+		if closer, ok := instance.Provider.(io.Closer); ok {
+			closer.Close()
+		}
+
+		return nil, fmt.Errorf("%s provider: %w", name, err)
+	}
+
+	return instance.Provider, nil
 }
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryProviderIsConfiguredFromEnvironment(t *testing.T) {
+	t.Setenv("DID_PROVIDER", "memory")
+	t.Setenv("MEMORY_DIDS", "Alice.example.com@did:plc:example001")
+	t.Setenv("MEMORY_DOMAINS", "Example.com")
+
+	config, err := ConfigFromEnvironment()
+
+	assert.Nil(t, err)
+
+	memory, _ := ProviderAs[*InMemoryProvider](config.Provider)
+	dids, _ := memory.ListDecentralizedIDs(context.Background())
+	assert.Equal(t, MapOfDids{"alice.example.com": "did:plc:example001"}, dids)
+}
+
+func TestMemoryProviderRejectsInvalidConfiguration(t *testing.T) {
+	tests := []struct {
+		dids    string
+		domains string
+	}{
+		{dids: "alice@did:plc:example001", domains: "example.com"},
+		{dids: "alice.example.com@plc:example001", domains: "example.com"},
+		{dids: "alice.example.com@did:plc:example001", domains: "example.local"},
+	}
+
+	for _, test := range tests {
+		t.Setenv("DID_PROVIDER", "memory")
+		t.Setenv("MEMORY_DIDS", test.dids)
+		t.Setenv("MEMORY_DOMAINS", test.domains)
+
+		_, err := ConfigFromEnvironment()
+
+		assert.NotNil(t, err, "MEMORY_DIDS=%s MEMORY_DOMAINS=%s", test.dids, test.domains)
+	}
+}
+
+func TestInvalidDatabaseURLIsRejected(t *testing.T) {
+	t.Setenv("DID_PROVIDER", "postgres")
+	t.Setenv("DATABASE_URL", "postgres://%zz")
+
+	_, err := ConfigFromEnvironment()
+
+	assert.NotNil(t, err)
+}
+
+func TestMemoryProviderIsConfiguredWithDomainSettings(t *testing.T) {
+	t.Setenv("DID_PROVIDER", "memory")
+	t.Setenv("MEMORY_DIDS", "alice.example.com@did:plc:example001")
+	t.Setenv("MEMORY_DOMAINS", "example.com")
+	t.Setenv("MEMORY_DOMAIN_SETTINGS", `{"example.com": {"redirectDidTemplate": "https://example.com/{did}", "redirectStatus": 302}}`)
+
+	config, err := ConfigFromEnvironment()
+
+	assert.Nil(t, err)
+
+	memory, _ := ProviderAs[*InMemoryProvider](config.Provider)
+	settings, _ := memory.GetDomainSettings(context.Background(), "example.com")
+	assert.Equal(t, DomainSettings{RedirectDIDTemplate: "https://example.com/{did}", RedirectStatus: 302}, settings)
+}
+
+func TestMemoryProviderRejectsInvalidDomainSettings(t *testing.T) {
+	tests := map[string]string{
+		"unlisted domain": `{"example.net": {"redirectStatus": 302}}`,
+		"invalid status":  `{"example.com": {"redirectStatus": 200}}`,
+		"invalid JSON":    `{"example.com"`,
+	}
+
+	for name, settings := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("DID_PROVIDER", "memory")
+			t.Setenv("MEMORY_DIDS", "alice.example.com@did:plc:example001")
+			t.Setenv("MEMORY_DOMAINS", "example.com")
+			t.Setenv("MEMORY_DOMAIN_SETTINGS", settings)
+
+			_, err := ConfigFromEnvironment()
+
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestInvalidRedirectTemplateIsRejected(t *testing.T) {
+	t.Setenv("DID_PROVIDER", "memory")
+	t.Setenv("MEMORY_DIDS", "alice.example.com@did:plc:example001")
+	t.Setenv("MEMORY_DOMAINS", "example.com")
+	t.Setenv("REDIRECT_DID_TEMPLATE", "https://example.com/{did|shout}")
+
+	_, err := ConfigFromEnvironment()
+
+	assert.NotNil(t, err)
+}
+
+func TestRedirectResponsesAreConfiguredFromEnvironment(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		valid bool
+	}{
+		{"REDIRECT_DID_STATUS", "301", true},
+		{"REDIRECT_DID_STATUS", "200", false},
+		{"REDIRECT_HANDLE_STATUS", "308", true},
+		{"REDIRECT_HANDLE_STATUS", "404", false},
+		{"REDIRECT_HEAD", "status", true},
+		{"REDIRECT_OTHER_METHODS", "reject", true},
+		{"REDIRECT_OTHER_METHODS", "ignore", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name+"="+test.value, func(t *testing.T) {
+			t.Setenv("DID_PROVIDER", "memory")
+			t.Setenv("MEMORY_DIDS", "alice.example.com@did:plc:example001")
+			t.Setenv("MEMORY_DOMAINS", "example.com")
+			t.Setenv(test.name, test.value)
+
+			_, err := ConfigFromEnvironment()
+
+			assert.Equal(t, test.valid, err == nil, "%v", err)
+		})
+	}
+}
+
+func TestMemoryProviderIsConfiguredWithDIDDocuments(t *testing.T) {
+	t.Setenv("DID_PROVIDER", "memory")
+	t.Setenv("MEMORY_DIDS", "bob.example.com@did:plc:example002")
+	t.Setenv("MEMORY_DOMAINS", "example.com")
+	t.Setenv("MEMORY_DID_DOCUMENTS", `{"alice.example.com": {"id": "did:web:alice.example.com", "alsoKnownAs": ["at://alice.example.com"]}}`)
+
+	config, err := ConfigFromEnvironment()
+
+	assert.Nil(t, err)
+
+	did, _ := config.Provider.GetDecentralizedIDForHandle(context.Background(), Handle{Domain: "example.com", Username: "alice"})
+	assert.Equal(t, DecentralizedID("did:web:alice.example.com"), did)
+}
+
+func TestRoutesProviderIsConfiguredFromEnvironment(t *testing.T) {
+	t.Setenv("DID_PROVIDER", "routes")
+	t.Setenv("DOMAIN_ROUTES", "example.com:ours,*.example.net:customer")
+	t.Setenv("PROVIDERS_OURS_DID_PROVIDER", "memory")
+	t.Setenv("PROVIDERS_OURS_MEMORY_DIDS", "alice.example.com@did:plc:example001")
+	t.Setenv("PROVIDERS_OURS_MEMORY_DOMAINS", "example.com")
+	t.Setenv("PROVIDERS_CUSTOMER_DID_PROVIDER", "memory")
+	t.Setenv("PROVIDERS_CUSTOMER_MEMORY_DIDS", "bob.eu.example.net@did:plc:example002")
+	t.Setenv("PROVIDERS_CUSTOMER_MEMORY_DOMAINS", "eu.example.net")
+
+	config, err := ConfigFromEnvironment()
+
+	assert.Nil(t, err)
+
+	did, _ := config.Provider.GetDecentralizedIDForHandle(context.Background(), Handle{Domain: "example.com", Username: "alice"})
+	assert.Equal(t, DecentralizedID("did:plc:example001"), did)
+
+	did, _ = config.Provider.GetDecentralizedIDForHandle(context.Background(), Handle{Domain: "eu.example.net", Username: "bob"})
+	assert.Equal(t, DecentralizedID("did:plc:example002"), did)
+}
+
+func TestRoutesProviderRejectsInvalidConfiguration(t *testing.T) {
+	tests := []struct {
+		name        string
+		environment map[string]string
+	}{
+		{"no routes", map[string]string{}},
+		{"no provider", map[string]string{"DOMAIN_ROUTES": "example.com:ours"}},
+		{"invalid provider", map[string]string{
+			"DOMAIN_ROUTES":               "example.com:ours",
+			"PROVIDERS_OURS_DID_PROVIDER": "memory",
+		}},
+		{"nested routes", map[string]string{
+			"DOMAIN_ROUTES":                "example.com:ours",
+			"PROVIDERS_OURS_DID_PROVIDER":  "routes",
+			"PROVIDERS_OURS_DOMAIN_ROUTES": "example.com:ours",
+		}},
+		{"cache of route", map[string]string{
+			"DOMAIN_ROUTES":               "example.com:ours",
+			"PROVIDERS_OURS_DID_PROVIDER": "memory",
+			"PROVIDERS_OURS_CACHE_TTL":    "1m",
+		}},
+		{"notify channel of route", map[string]string{
+			"DOMAIN_ROUTES":                          "example.com:ours",
+			"PROVIDERS_OURS_DID_PROVIDER":            "postgres",
+			"PROVIDERS_OURS_DATABASE_URL":            "postgres://localhost/ours",
+			"PROVIDERS_OURS_DATABASE_NOTIFY_CHANNEL": "handles_changes",
+		}},
+		{"verification of route", map[string]string{
+			"DOMAIN_ROUTES":                 "example.com:ours",
+			"PROVIDERS_OURS_DID_PROVIDER":   "memory",
+			"PROVIDERS_OURS_VERIFY_HANDLES": "true",
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("DID_PROVIDER", "routes")
+
+			for name, value := range test.environment {
+				t.Setenv(name, value)
+			}
+
+			_, err := ConfigFromEnvironment()
+
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestRoutesProviderNamesSettingsRoutesDoNotUse(t *testing.T) {
+	t.Setenv("DID_PROVIDER", "routes")
+	t.Setenv("DOMAIN_ROUTES", "example.com:ours")
+	t.Setenv("PROVIDERS_OURS_DID_PROVIDER", "memory")
+	t.Setenv("PROVIDERS_OURS_MEMORY_DOMAINS", "example.com")
+	t.Setenv("PROVIDERS_OURS_CACHE_TTL", "1m")
+	t.Setenv("PROVIDERS_OURS_VERIFY_INTERVAL", "5m")
+
+	_, err := ConfigFromEnvironment()
+
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "`PROVIDERS_OURS_CACHE_TTL`, `PROVIDERS_OURS_VERIFY_INTERVAL`")
+	}
+}
+
+func TestUpstreamProviderRejectsInvalidConfiguration(t *testing.T) {
+	tests := []struct {
+		name        string
+		environment map[string]string
+	}{
+		{"no url", map[string]string{}},
+		{"not http", map[string]string{"UPSTREAM_URL": "ftp://example.com/{handle}"}},
+		{"request token", map[string]string{"UPSTREAM_URL": "https://example.com/{request.path}"}},
+		{"host request token", map[string]string{"UPSTREAM_URL": "https://example.com", "UPSTREAM_HOST": "{request.host}"}},
+		{"invalid api", map[string]string{"UPSTREAM_URL": "https://example.com", "UPSTREAM_API": "graphql"}},
+		{"invalid domain", map[string]string{"UPSTREAM_URL": "https://example.com", "UPSTREAM_DOMAINS": "example.local"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("DID_PROVIDER", "upstream")
+
+			for name, value := range test.environment {
+				t.Setenv(name, value)
+			}
+
+			_, err := ConfigFromEnvironment()
+
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestDNSServerIsConfiguredFromEnvironment(t *testing.T) {
+	t.Setenv("DID_PROVIDER", "memory")
+	t.Setenv("MEMORY_DIDS", "alice.example.com@did:plc:example001")
+	t.Setenv("MEMORY_DOMAINS", "example.com")
+	t.Setenv("DNS_ADDRESS", ":5353")
+	t.Setenv("DNS_HOST_ADDRESSES", "192.0.2.1,2001:db8::1")
+	t.Setenv("DNS_NAMESERVERS", "ns1.example.org,ns2.example.org")
+
+	config, err := ConfigFromEnvironment()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []net.IP{net.ParseIP("192.0.2.1"), net.ParseIP("2001:db8::1")}, config.DNSHostAddresses)
+	assert.Equal(t, []string{"ns1.example.org", "ns2.example.org"}, config.DNSNameservers)
+
+	t.Setenv("DNS_NAMESERVERS", "")
+
+	_, err = ConfigFromEnvironment()
+	assert.Error(t, err)
+
+	t.Setenv("DNS_NAMESERVERS", "ns1.example.org")
+	t.Setenv("DNS_HOST_ADDRESSES", "example.org")
+
+	_, err = ConfigFromEnvironment()
+	assert.Error(t, err)
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// ReadConfigFile reads the settings of a YAML or TOML file as environment
+// variables. Nested keys are joined, so `cache: {ttl: 5m}` sets `CACHE_TTL`,
+// and lists and maps are written in the format used by environment variables.
+func ReadConfigFile(path string) (map[string]string, error) {
+	contents, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not read config file: %w", err)
+	}
+
+	values := make(map[string]any)
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(contents, &values)
+	case ".toml":
+		err = toml.Unmarshal(contents, &values)
+	default:
+		return nil, fmt.Errorf("config file %s is not YAML (.yaml, .yml) or TOML (.toml)", path)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("could not parse config file %s: %w", path, err)
+	}
+
+	environment := make(map[string]string)
+
+	if err := flattenConfig("", values, configFields(), environment); err != nil {
+		return nil, fmt.Errorf("config file %s is not valid: %w", path, err)
+	}
+
+	return environment, nil
+}
+
+// configFields maps the environment variables read by Config to their fields.
+func configFields() map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+
+	for _, field := range reflect.VisibleFields(reflect.TypeFor[Config]()) {
+		name, _, _ := strings.Cut(field.Tag.Get("env"), ",")
+
+		if name != "" && name != "-" {
+			fields[name] = field
+		}
+	}
+
+	return fields
+}
+
+func configKeyToEnvironmentName(key string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "_", ".", "_", " ", "_").Replace(key))
+}
+
+// configFieldName is the variable read by Config for a setting, removing the
+// `PROVIDERS_<NAME>_` prefix of the settings of a routed provider.
+func configFieldName(name string) string {
+	if rest, ok := strings.CutPrefix(name, "PROVIDERS_"); ok {
+		if _, setting, found := strings.Cut(rest, "_"); found {
+			return setting
+		}
+	}
+
+	return name
+}
+
+func flattenConfig(prefix string, values map[string]any, fields map[string]reflect.StructField, environment map[string]string) error {
+	for key, value := range values {
+		name := prefix + configKeyToEnvironmentName(key)
+
+		if field, ok := fields[configFieldName(name)]; ok {
+			formatted, err := formatConfigValue(value, field)
+
+			if err != nil {
+				return fmt.Errorf("`%s`: %w", name, err)
+			}
+
+			environment[name] = formatted
+			continue
+		}
+
+		nested, ok := value.(map[string]any)
+
+		if !ok {
+			return fmt.Errorf("`%s` is not a setting", name)
+		}
+
+		if err := flattenConfig(name+"_", nested, fields, environment); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func formatConfigValue(value any, field reflect.StructField) (string, error) {
+	separator := field.Tag.Get("envSeparator")
+
+	if separator == "" {
+		separator = ","
+	}
+
+	// Settings parsed from text, such as JSON, are given nested values as JSON.
+	if reflect.PointerTo(field.Type).Implements(reflect.TypeFor[encoding.TextUnmarshaler]()) {
+		switch value.(type) {
+		case map[string]any, []any:
+			encoded, err := json.Marshal(value)
+			return string(encoded), err
+		}
+	}
+
+	var items []string
+
+	switch value := value.(type) {
+	case nil:
+		return "", nil
+	case []any:
+		for _, item := range value {
+			formatted, err := formatConfigScalar(item)
+
+			if err != nil {
+				return "", err
+			}
+
+			items = append(items, formatted)
+		}
+	case map[string]any:
+		keyValueSeparator := field.Tag.Get("envKeyValSeparator")
+
+		if keyValueSeparator == "" {
+			keyValueSeparator = ":"
+		}
+
+		for key, item := range value {
+			formatted, err := formatConfigScalar(item)
+
+			if err != nil {
+				return "", err
+			}
+
+			items = append(items, key+keyValueSeparator+formatted)
+		}
+
+		slices.Sort(items)
+	default:
+		return fmt.Sprint(value), nil
+	}
+
+	return strings.Join(items, separator), nil
+}
+
+func formatConfigScalar(value any) (string, error) {
+	switch value.(type) {
+	case map[string]any, []any:
+		return "", fmt.Errorf("%v cannot be nested", value)
+	default:
+		return fmt.Sprint(value), nil
+	}
+}
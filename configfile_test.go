@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTestConfigFile(t *testing.T, name string, contents string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, []byte(contents), 0o600))
+
+	return path
+}
+
+func TestConfigFileIsReadAsEnvironmentVariables(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": `
+did_provider: memory
+memory:
+  dids:
+    alice.example.com: did:plc:example001
+  domains:
+    - example.com
+    - example.net
+cache:
+  ttl: 5m
+redirect-did-template: https://example.com/{did}
+`,
+		"config.toml": `
+did_provider = "memory"
+redirect-did-template = "https://example.com/{did}"
+
+[memory]
+domains = ["example.com", "example.net"]
+
+[memory.dids]
+"alice.example.com" = "did:plc:example001"
+
+[cache]
+ttl = "5m"
+`,
+	}
+
+	for name, contents := range files {
+		settings, err := ReadConfigFile(writeTestConfigFile(t, name, contents))
+
+		assert.NoError(t, err, name)
+		assert.Equal(t, map[string]string{
+			"DID_PROVIDER":          "memory",
+			"MEMORY_DIDS":           "alice.example.com@did:plc:example001",
+			"MEMORY_DOMAINS":        "example.com,example.net",
+			"CACHE_TTL":             "5m",
+			"REDIRECT_DID_TEMPLATE": "https://example.com/{did}",
+		}, settings, name)
+	}
+}
+
+func TestConfigFileRejectsUnknownSettings(t *testing.T) {
+	files := map[string]string{
+		"unknown.yaml": "cache:\n  tll: 5m\n",
+		"nested.yaml":  "memory:\n  domains:\n    - [example.com]\n",
+		"config.json":  `{"did_provider": "memory"}`,
+		"invalid.toml": "did_provider = ",
+		"missing.yaml": "",
+	}
+
+	for name, contents := range files {
+		path := writeTestConfigFile(t, name, contents)
+
+		if name == "missing.yaml" {
+			path += ".missing"
+		}
+
+		_, err := ReadConfigFile(path)
+
+		assert.Error(t, err, name)
+	}
+}
+
+func TestEnvironmentVariablesTakePrecedenceOverConfigFile(t *testing.T) {
+	t.Setenv("CONFIG_FILE", writeTestConfigFile(t, "config.yaml", `
+did_provider: memory
+memory:
+  dids:
+    alice.example.com: did:plc:example001
+  domains: [example.com]
+cache:
+  ttl: 5m
+`))
+	t.Setenv("CACHE_TTL", "1m")
+
+	config, err := ConfigFromEnvironment()
+
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, config.CacheTTL)
+
+	did, _ := config.Provider.GetDecentralizedIDForHandle(context.Background(), Handle{Domain: "example.com", Username: "alice"})
+	assert.Equal(t, DecentralizedID("did:plc:example001"), did)
+}
+
+func TestConfigFileWritesNestedDomainSettingsAsJSON(t *testing.T) {
+	settings, err := ReadConfigFile(writeTestConfigFile(t, "config.yaml", `
+memory:
+  domain_settings:
+    example.com:
+      redirectStatus: 302
+`))
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"example.com": {"redirectStatus": 302}}`, settings["MEMORY_DOMAIN_SETTINGS"])
+}
+
+func TestConfigFileReadsTheSettingsOfRoutedProviders(t *testing.T) {
+	settings, err := ReadConfigFile(writeTestConfigFile(t, "config.yaml", `
+did_provider: routes
+domain_routes:
+  "*.example.com": customer
+providers:
+  customer:
+    did_provider: postgres
+    database:
+      url: postgres://localhost/customer
+`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"DID_PROVIDER":                    "routes",
+		"DOMAIN_ROUTES":                   "*.example.com:customer",
+		"PROVIDERS_CUSTOMER_DID_PROVIDER": "postgres",
+		"PROVIDERS_CUSTOMER_DATABASE_URL": "postgres://localhost/customer",
+	}, settings)
+
+	_, err = ReadConfigFile(writeTestConfigFile(t, "config.yaml", `
+providers:
+  customer:
+    unknown: true
+`))
+
+	assert.Error(t, err)
+}
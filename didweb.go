@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+)
+
+var didDocumentContext = []string{
+	"https://www.w3.org/ns/did/v1",
+	"https://w3id.org/security/multikey/v1",
+	"https://w3id.org/security/suites/secp256k1-2019/v1",
+}
+
+type VerificationMethod struct {
+	ID                 string `json:"id" yaml:"id"`
+	Type               string `json:"type" yaml:"type"`
+	Controller         string `json:"controller" yaml:"controller"`
+	PublicKeyMultibase string `json:"publicKeyMultibase" yaml:"publicKeyMultibase"`
+}
+
+type DIDService struct {
+	ID              string `json:"id" yaml:"id"`
+	Type            string `json:"type" yaml:"type"`
+	ServiceEndpoint string `json:"serviceEndpoint" yaml:"serviceEndpoint"`
+}
+
+// DIDWebID is the did:web of a handle hosted by the server.
+func DIDWebID(handle Handle) DecentralizedID {
+	return DecentralizedID("did:web:" + handle.String())
+}
+
+// ValidateDIDDocument checks a document can be served as the did:web of a
+// handle.
+func ValidateDIDDocument(handle Handle, document DIDDocument) error {
+	if document.ID != string(DIDWebID(handle)) {
+		return fmt.Errorf("document id %q must be %s", document.ID, DIDWebID(handle))
+	}
+
+	for _, method := range document.VerificationMethod {
+		if method.ID == "" || method.Type == "" || method.PublicKeyMultibase == "" {
+			return fmt.Errorf("verification method %q must have an id, type and publicKeyMultibase", method.ID)
+		}
+	}
+
+	for _, service := range document.Service {
+		if service.ID == "" || service.Type == "" {
+			return fmt.Errorf("service %q must have an id and type", service.ID)
+		}
+
+		if endpoint, err := url.Parse(service.ServiceEndpoint); err != nil || endpoint.Scheme != "https" {
+			return fmt.Errorf("service %q must have an https serviceEndpoint", service.ID)
+		}
+	}
+
+	return nil
+}
+
+// MapOfDIDDocuments is read from JSON (`MEMORY_DID_DOCUMENTS`).
+type MapOfDIDDocuments map[Hostname]DIDDocument
+
+func (documents *MapOfDIDDocuments) UnmarshalText(text []byte) error {
+	return json.Unmarshal(text, (*map[Hostname]DIDDocument)(documents))
+}
+
+// ProvidesDIDDocuments is implemented by providers which store did:web
+// documents for handles; a handle without a document has a nil document.
+type ProvidesDIDDocuments interface {
+	GetDIDDocument(ctx context.Context, handle Handle) (*DIDDocument, error)
+}
+
+// ManagesDIDDocuments is implemented by providers whose documents can be
+// changed through the admin API.
+type ManagesDIDDocuments interface {
+	SetDIDDocument(ctx context.Context, handle Handle, document DIDDocument) error
+	DeleteDIDDocument(ctx context.Context, handle Handle) error
+}
+
+// DIDWebProvider resolves handles which have a document, but no other
+// Decentralized ID, to their own did:web.
+type DIDWebProvider struct {
+	provider  ProvidesDecentralizedIDs
+	documents ProvidesDIDDocuments
+}
+
+func NewDIDWebProvider(provider ProvidesDecentralizedIDs, documents ProvidesDIDDocuments) *DIDWebProvider {
+	return &DIDWebProvider{provider, documents}
+}
+
+func (didWeb *DIDWebProvider) GetDecentralizedIDForHandle(ctx context.Context, handle Handle) (DecentralizedID, error) {
+	did, err := didWeb.provider.GetDecentralizedIDForHandle(ctx, handle)
+
+	if err != nil || did != "" {
+		return did, err
+	}
+
+	document, err := didWeb.documents.GetDIDDocument(ctx, handle)
+
+	if err != nil || document == nil {
+		return "", err
+	}
+
+	return DIDWebID(handle), nil
+}
+
+func (didWeb *DIDWebProvider) CanProvideForDomain(ctx context.Context, domain Domain) (bool, error) {
+	return didWeb.provider.CanProvideForDomain(ctx, domain)
+}
+
+func (didWeb *DIDWebProvider) IsHealthy(ctx context.Context) (bool, string) {
+	return didWeb.provider.IsHealthy(ctx)
+}
+
+func (didWeb *DIDWebProvider) Unwrap() ProvidesDecentralizedIDs {
+	return didWeb.provider
+}
+
+func (didWeb *DIDWebProvider) Close() error {
+	if closer, ok := didWeb.provider.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+func ServeDIDDocument(documents ProvidesDIDDocuments) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		handle := c.MustGet("handle").(Handle)
+
+		document, err := documents.GetDIDDocument(c, handle)
+
+		if err != nil {
+			_ = c.AbortWithError(http.StatusBadGateway, err)
+			return
+		}
+
+		if document == nil {
+			c.String(http.StatusNotFound, "DID document not found for %s", handle.String())
+			return
+		}
+
+		c.JSON(http.StatusOK, struct {
+			Context []string `json:"@context"`
+			DIDDocument
+		}{didDocumentContext, *document})
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestDIDDocument(hostname string) DIDDocument {
+	return DIDDocument{
+		ID:          "did:web:" + hostname,
+		AlsoKnownAs: []string{"at://" + hostname},
+		VerificationMethod: []VerificationMethod{{
+			ID:                 "did:web:" + hostname + "#atproto",
+			Type:               "Multikey",
+			Controller:         "did:web:" + hostname,
+			PublicKeyMultibase: "zQ3shXjHeiBuRCKmM36cuYnm7YEMzhGnCmCyW92sRJ9pribSF",
+		}},
+		Service: []DIDService{{
+			ID:              "#atproto_pds",
+			Type:            "AtprotoPersonalDataServer",
+			ServiceEndpoint: "https://pds.example.com",
+		}},
+	}
+}
+
+func newTestDIDWebProvider() *InMemoryProvider {
+	provider := NewInMemoryProvider(MapOfDids{
+		"bob.example.com": "did:plc:example002",
+	}, MapOfDomains{"example.com": true})
+
+	_ = provider.SetDIDDocument(context.Background(), Handle{Domain: "example.com", Username: "alice"}, newTestDIDDocument("alice.example.com"))
+	_ = provider.SetDIDDocument(context.Background(), Handle{Domain: "example.com", Username: "bob"}, newTestDIDDocument("bob.example.com"))
+
+	return provider
+}
+
+func TestDIDDocumentIsValidated(t *testing.T) {
+	handle := Handle{Domain: "example.com", Username: "alice"}
+
+	assert.NoError(t, ValidateDIDDocument(handle, newTestDIDDocument("alice.example.com")))
+	assert.Error(t, ValidateDIDDocument(handle, newTestDIDDocument("bob.example.com")))
+
+	insecure := newTestDIDDocument("alice.example.com")
+	insecure.Service[0].ServiceEndpoint = "http://pds.example.com"
+	assert.Error(t, ValidateDIDDocument(handle, insecure))
+
+	keyless := newTestDIDDocument("alice.example.com")
+	keyless.VerificationMethod[0].PublicKeyMultibase = ""
+	assert.Error(t, ValidateDIDDocument(handle, keyless))
+}
+
+func TestHandleWithDocumentResolvesToItsDIDWeb(t *testing.T) {
+	memory := newTestDIDWebProvider()
+	provider := NewDIDWebProvider(memory, memory)
+
+	tests := map[Username]DecentralizedID{
+		"alice": "did:web:alice.example.com",
+		"bob":   "did:plc:example002",
+		"carol": "",
+	}
+
+	for username, expected := range tests {
+		did, err := provider.GetDecentralizedIDForHandle(context.Background(), Handle{Domain: "example.com", Username: username})
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected, did, username)
+	}
+}
+
+func TestDIDDocumentIsServedForHandle(t *testing.T) {
+	memory := newTestDIDWebProvider()
+	router := gin.New()
+
+	AddApplicationRoutes(router, Config{
+		Provider: NewDIDWebProvider(memory, memory),
+		Logger:   testFileLogger,
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/.well-known/did.json", nil)
+	req.Host = "alice.example.com"
+	router.ServeHTTP(res, req)
+
+	var document map[string]any
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.NoError(t, json.Unmarshal(res.Body.Bytes(), &document))
+	assert.Equal(t, "did:web:alice.example.com", document["id"])
+	assert.Contains(t, document["@context"], "https://www.w3.org/ns/did/v1")
+
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/.well-known/atproto-did", nil)
+	req.Host = "alice.example.com"
+	router.ServeHTTP(res, req)
+
+	assert.Equal(t, "did:web:alice.example.com", res.Body.String())
+
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/.well-known/did.json", nil)
+	req.Host = "carol.example.com"
+	router.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusNotFound, res.Code)
+}
+
+func TestAdminManagesDIDDocumentsThroughCache(t *testing.T) {
+	memory := newTestAdminProvider()
+	router := NewTestAdminEnvironment(NewCachingProvider(NewDIDWebProvider(memory, memory), 10, time.Hour, time.Hour, time.Hour))
+
+	resolve := func() string {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/.well-known/atproto-did", nil)
+		req.Host = "carol.example.com"
+		router.ServeHTTP(res, req)
+
+		return res.Body.String()
+	}
+
+	assert.Equal(t, "Decentralized ID not found for carol.example.com", resolve())
+
+	body, _ := json.Marshal(newTestDIDDocument("carol.example.com"))
+
+	res := adminRequest(router, "PUT", "/admin/handles/carol.example.com/document", string(body))
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "did:web:carol.example.com", resolve())
+
+	res = adminRequest(router, "GET", "/admin/handles/carol.example.com/document", "")
+	assert.JSONEq(t, string(body), res.Body.String())
+
+	res = adminRequest(router, "PUT", "/admin/handles/carol.example.com/document", `{"id": "did:web:alice.example.com"}`)
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+
+	res = adminRequest(router, "DELETE", "/admin/handles/carol.example.com/document", "")
+	assert.Equal(t, http.StatusNoContent, res.Code)
+	assert.Equal(t, "Decentralized ID not found for carol.example.com", resolve())
+}
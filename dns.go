@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const atprotoRecordPrefix = "_atproto."
+
+// DNSHandler answers `TXT _atproto.<handle>` queries with `did=<did>` so that
+// handles on domains delegated to the server are resolved by the DNS method,
+// and `A` and `AAAA` queries of handles with the addresses of the server so
+// that they are also resolved by the HTTPS method. Each provided domain is
+// answered as the apex of a zone, with `SOA` and `NS` records.
+type DNSHandler struct {
+	provider    ProvidesDecentralizedIDs
+	ttl         time.Duration
+	addresses   []net.IP
+	nameservers []string
+	logger      *slog.Logger
+}
+
+func NewDNSHandler(provider ProvidesDecentralizedIDs, ttl time.Duration, addresses []net.IP, nameservers []string, logger *slog.Logger) *DNSHandler {
+	fqdns := make([]string, len(nameservers))
+
+	for i, nameserver := range nameservers {
+		fqdns[i] = dns.Fqdn(strings.ToLower(nameserver))
+	}
+
+	return &DNSHandler{provider, ttl, addresses, fqdns, logger}
+}
+
+// NewDNSServers creates a UDP and a TCP server listening on the same address.
+func NewDNSServers(address string, handler dns.Handler) []*dns.Server {
+	return []*dns.Server{
+		{Addr: address, Net: "udp", Handler: handler},
+		{Addr: address, Net: "tcp", Handler: handler},
+	}
+}
+
+func (handler *DNSHandler) ServeDNS(w dns.ResponseWriter, request *dns.Msg) {
+	response := new(dns.Msg)
+	response.SetReply(request)
+	response.Authoritative = true
+
+	if len(request.Question) == 1 {
+		handler.answer(response, request.Question[0])
+	} else {
+		response.Rcode = dns.RcodeFormatError
+	}
+
+	if err := w.WriteMsg(response); err != nil {
+		handler.logger.Error("could not write DNS response", "error", err)
+	}
+}
+
+func (handler *DNSHandler) header(name string, rrtype uint16) dns.RR_Header {
+	return dns.RR_Header{Name: name, Rrtype: rrtype, Class: dns.ClassINET, Ttl: uint32(handler.ttl.Seconds())}
+}
+
+// soa is the start of authority of a provided domain, which is also used to
+// cache answers which have no records.
+func (handler *DNSHandler) soa(domain Domain) dns.RR {
+	zone := dns.Fqdn(string(domain))
+	ttl := uint32(handler.ttl.Seconds())
+
+	return &dns.SOA{
+		Hdr:     handler.header(zone, dns.TypeSOA),
+		Ns:      handler.nameservers[0],
+		Mbox:    "hostmaster." + zone,
+		Serial:  1,
+		Refresh: ttl,
+		Retry:   ttl,
+		Expire:  7 * 24 * 60 * 60,
+		Minttl:  ttl,
+	}
+}
+
+// addressRecords are the `A` or `AAAA` records of the server for a name.
+func (handler *DNSHandler) addressRecords(name string, qtype uint16) []dns.RR {
+	var records []dns.RR
+
+	for _, address := range handler.addresses {
+		if ipv4 := address.To4(); ipv4 != nil && (qtype == dns.TypeA || qtype == dns.TypeANY) {
+			records = append(records, &dns.A{Hdr: handler.header(name, dns.TypeA), A: ipv4})
+		} else if ipv4 == nil && (qtype == dns.TypeAAAA || qtype == dns.TypeANY) {
+			records = append(records, &dns.AAAA{Hdr: handler.header(name, dns.TypeAAAA), AAAA: address})
+		}
+	}
+
+	return records
+}
+
+func (handler *DNSHandler) answer(response *dns.Msg, question dns.Question) {
+	name := strings.ToLower(dns.Fqdn(question.Name))
+	hostname, isAtprotoRecord := strings.CutPrefix(name, atprotoRecordPrefix)
+	hostname = strings.TrimSuffix(hostname, ".")
+
+	if question.Qclass != dns.ClassINET {
+		response.Rcode = dns.RcodeRefused
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if !isAtprotoRecord {
+		isDomain, err := handler.provider.CanProvideForDomain(ctx, Domain(hostname))
+
+		if err != nil {
+			handler.logger.Error("could not check domain for DNS query", "domain", hostname, "error", err)
+			response.Rcode = dns.RcodeServerFailure
+			return
+		}
+
+		if isDomain {
+			handler.answerDomain(response, name, Domain(hostname), question.Qtype)
+			return
+		}
+	}
+
+	handle, err := HostnameToHandle(hostname)
+
+	if err != nil {
+		response.Rcode = dns.RcodeRefused
+		return
+	}
+
+	handle, err = HandleOnProvidedDomain(ctx, handler.provider, handle)
+
+	var did DecentralizedID
+
+	if err == nil {
+		did, err = GetValidDecentralizedIDForHandle(ctx, handler.provider, handle)
+	}
+
+	if errors.Is(err, (*CannotGetHandelsFromDomainError)(nil)) {
+		response.Rcode = dns.RcodeRefused
+		return
+	}
+
+	if err != nil {
+		handler.logger.Error("could not get Decentralized ID for DNS query", "handle", handle.String(), "error", err)
+		response.Rcode = dns.RcodeServerFailure
+		return
+	}
+
+	if did == "" {
+		response.Rcode = dns.RcodeNameError
+		response.Ns = []dns.RR{handler.soa(handle.Domain)}
+		return
+	}
+
+	switch {
+	case isAtprotoRecord && (question.Qtype == dns.TypeTXT || question.Qtype == dns.TypeANY):
+		response.Answer = []dns.RR{&dns.TXT{
+			Hdr: handler.header(name, dns.TypeTXT),
+			Txt: []string{"did=" + string(did)},
+		}}
+	case !isAtprotoRecord:
+		response.Answer = handler.addressRecords(name, question.Qtype)
+	}
+
+	if len(response.Answer) == 0 {
+		response.Ns = []dns.RR{handler.soa(handle.Domain)}
+	}
+}
+
+// answerDomain answers for the apex of a provided domain.
+func (handler *DNSHandler) answerDomain(response *dns.Msg, name string, domain Domain, qtype uint16) {
+	if qtype == dns.TypeSOA || qtype == dns.TypeANY {
+		response.Answer = append(response.Answer, handler.soa(domain))
+	}
+
+	if qtype == dns.TypeNS || qtype == dns.TypeANY {
+		for _, nameserver := range handler.nameservers {
+			response.Answer = append(response.Answer, &dns.NS{Hdr: handler.header(name, dns.TypeNS), Ns: nameserver})
+		}
+	}
+
+	response.Answer = append(response.Answer, handler.addressRecords(name, qtype)...)
+
+	if len(response.Answer) == 0 {
+		response.Ns = []dns.RR{handler.soa(domain)}
+	}
+}
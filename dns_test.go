@@ -0,0 +1,132 @@
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+func NewTestDNSServer(t *testing.T) string {
+	return newTestDNSServerFor(t, newTestAdminProvider())
+}
+
+func newTestDNSServerFor(t *testing.T, provider ProvidesDecentralizedIDs) string {
+	connection, err := net.ListenPacket("udp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+
+	server := &dns.Server{
+		PacketConn:        connection,
+		Handler:           NewDNSHandler(provider, time.Minute, []net.IP{net.ParseIP("192.0.2.1"), net.ParseIP("2001:db8::1")}, []string{"ns1.example.org", "ns2.example.org"}, testFileLogger),
+		NotifyStartedFunc: func() { close(started) },
+	}
+
+	go func() { _ = server.ActivateAndServe() }()
+	t.Cleanup(func() { _ = server.Shutdown() })
+
+	<-started
+
+	return connection.LocalAddr().String()
+}
+
+func queryTestDNSServer(t *testing.T, address string, name string, qtype uint16) *dns.Msg {
+	query := new(dns.Msg)
+	query.SetQuestion(name, qtype)
+
+	response, _, err := new(dns.Client).Exchange(query, address)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return response
+}
+
+func TestDNSServerAnswersAtprotoRecordForKnownHandle(t *testing.T) {
+	address := NewTestDNSServer(t)
+
+	response := queryTestDNSServer(t, address, "_atproto.Alice.example.com.", dns.TypeTXT)
+
+	assert.Equal(t, dns.RcodeSuccess, response.Rcode)
+	assert.True(t, response.Authoritative)
+	assert.Len(t, response.Answer, 1)
+	assert.Equal(t, []string{"did=did:plc:example001"}, response.Answer[0].(*dns.TXT).Txt)
+	assert.Equal(t, uint32(60), response.Answer[0].Header().Ttl)
+}
+
+func TestDNSServerResponseCodes(t *testing.T) {
+	tests := []struct {
+		name         string
+		qtype        uint16
+		expectedCode int
+		expectedSOA  bool
+	}{
+		{name: "_atproto.carol.example.com.", qtype: dns.TypeTXT, expectedCode: dns.RcodeNameError, expectedSOA: true},
+		{name: "carol.example.com.", qtype: dns.TypeA, expectedCode: dns.RcodeNameError, expectedSOA: true},
+		{name: "_atproto.alice.example.net.", qtype: dns.TypeTXT, expectedCode: dns.RcodeRefused},
+		{name: "example.net.", qtype: dns.TypeSOA, expectedCode: dns.RcodeRefused},
+		{name: "alice.example.com.", qtype: dns.TypeTXT, expectedCode: dns.RcodeSuccess, expectedSOA: true},
+		{name: "_atproto.alice.example.com.", qtype: dns.TypeA, expectedCode: dns.RcodeSuccess, expectedSOA: true},
+		{name: "example.com.", qtype: dns.TypeTXT, expectedCode: dns.RcodeSuccess, expectedSOA: true},
+	}
+
+	address := NewTestDNSServer(t)
+
+	for _, test := range tests {
+		response := queryTestDNSServer(t, address, test.name, test.qtype)
+
+		assert.Equal(t, test.expectedCode, response.Rcode, "Query for %s", test.name)
+		assert.Empty(t, response.Answer, "Query for %s", test.name)
+
+		if test.expectedSOA {
+			assert.Len(t, response.Ns, 1, "Query for %s", test.name)
+			assert.Equal(t, "example.com.", response.Ns[0].(*dns.SOA).Hdr.Name, "Query for %s", test.name)
+		} else {
+			assert.Empty(t, response.Ns, "Query for %s", test.name)
+		}
+	}
+}
+
+func TestDNSServerAnswersAddressesForKnownHandle(t *testing.T) {
+	address := NewTestDNSServer(t)
+
+	response := queryTestDNSServer(t, address, "Alice.example.com.", dns.TypeA)
+
+	assert.Equal(t, dns.RcodeSuccess, response.Rcode)
+	assert.True(t, response.Authoritative)
+	assert.Len(t, response.Answer, 1)
+	assert.Equal(t, "192.0.2.1", response.Answer[0].(*dns.A).A.String())
+
+	response = queryTestDNSServer(t, address, "alice.example.com.", dns.TypeAAAA)
+
+	assert.Len(t, response.Answer, 1)
+	assert.Equal(t, "2001:db8::1", response.Answer[0].(*dns.AAAA).AAAA.String())
+}
+
+func TestDNSServerAnswersStartOfAuthorityForDomain(t *testing.T) {
+	address := NewTestDNSServer(t)
+
+	response := queryTestDNSServer(t, address, "example.com.", dns.TypeSOA)
+
+	assert.Equal(t, dns.RcodeSuccess, response.Rcode)
+	assert.True(t, response.Authoritative)
+	assert.Len(t, response.Answer, 1)
+
+	soa := response.Answer[0].(*dns.SOA)
+	assert.Equal(t, "ns1.example.org.", soa.Ns)
+	assert.Equal(t, "hostmaster.example.com.", soa.Mbox)
+	assert.Equal(t, uint32(60), soa.Minttl)
+
+	response = queryTestDNSServer(t, address, "example.com.", dns.TypeNS)
+
+	assert.Len(t, response.Answer, 2)
+	assert.Equal(t, "ns1.example.org.", response.Answer[0].(*dns.NS).Ns)
+	assert.Equal(t, "ns2.example.org.", response.Answer[1].(*dns.NS).Ns)
+}
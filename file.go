@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v3"
+)
+
+type handlesFile struct {
+	Dids      map[string]string         `json:"dids" yaml:"dids"`
+	Domains   []string                  `json:"domains" yaml:"domains"`
+	Settings  map[string]DomainSettings `json:"settings" yaml:"settings"`
+	Documents map[string]DIDDocument    `json:"documents" yaml:"documents"`
+}
+
+type FileProvider struct {
+	path    string
+	logger  *slog.Logger
+	watcher *fsnotify.Watcher
+	current atomic.Pointer[InMemoryProvider]
+}
+
+func NewFileProvider(path string, logger *slog.Logger) (*FileProvider, error) {
+	file := &FileProvider{path: filepath.Clean(path), logger: logger}
+
+	if err := file.reload(); err != nil {
+		return nil, err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Editors and tools like git replace files rather than writing to them, so
+	// the directory is watched in order to see the file being recreated.
+	if err := watcher.Add(filepath.Dir(file.path)); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	file.watcher = watcher
+
+	go file.watch()
+
+	return file, nil
+}
+
+func (file *FileProvider) GetDecentralizedIDForHandle(ctx context.Context, handle Handle) (DecentralizedID, error) {
+	return file.current.Load().GetDecentralizedIDForHandle(ctx, handle)
+}
+
+func (file *FileProvider) CanProvideForDomain(ctx context.Context, domain Domain) (bool, error) {
+	return file.current.Load().CanProvideForDomain(ctx, domain)
+}
+
+func (file *FileProvider) IsHealthy(ctx context.Context) (bool, string) {
+	healthy, status := file.current.Load().IsHealthy(ctx)
+
+	return healthy, fmt.Sprintf("%s from %s", status, file.path)
+}
+
+func (file *FileProvider) GetDomainSettings(ctx context.Context, domain Domain) (DomainSettings, error) {
+	return file.current.Load().GetDomainSettings(ctx, domain)
+}
+
+func (file *FileProvider) GetDIDDocument(ctx context.Context, handle Handle) (*DIDDocument, error) {
+	return file.current.Load().GetDIDDocument(ctx, handle)
+}
+
+func (file *FileProvider) Close() error {
+	return file.watcher.Close()
+}
+
+func (file *FileProvider) watch() {
+	for {
+		select {
+		case event, ok := <-file.watcher.Events:
+			if !ok {
+				return
+			}
+
+			if filepath.Clean(event.Name) != file.path || !event.Has(fsnotify.Write|fsnotify.Create) {
+				continue
+			}
+
+			if err := file.reload(); err != nil {
+				file.logger.Error("could not reload handles file, continuing with previous copy", "path", file.path, "error", err)
+				continue
+			}
+
+			file.logger.Info("reloaded handles file", "path", file.path)
+		case err, ok := <-file.watcher.Errors:
+			if !ok {
+				return
+			}
+
+			file.logger.Error("error watching handles file", "path", file.path, "error", err)
+		}
+	}
+}
+
+func (file *FileProvider) reload() error {
+	provider, err := LoadHandlesFile(file.path)
+
+	if err != nil {
+		return err
+	}
+
+	file.current.Store(provider)
+
+	return nil
+}
+
+func ReadHandlesFile(path string) (MapOfDids, MapOfDomains, error) {
+	contents, err := decodeHandlesFile(path)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dids, domains := contents.handles()
+
+	return dids, domains, nil
+}
+
+// LoadHandlesFile reads the handles, domains, domain settings and DID
+// documents of a file into a provider.
+func LoadHandlesFile(path string) (*InMemoryProvider, error) {
+	contents, err := decodeHandlesFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	provider := NewInMemoryProvider(contents.handles())
+
+	for domain, settings := range contents.Settings {
+		domain := Domain(strings.ToLower(domain))
+
+		if !provider.domains[domain] {
+			return nil, fmt.Errorf("handles file %s has settings for %s which is not one of its domains", path, domain)
+		}
+
+		if err := settings.Validate(); err != nil {
+			return nil, fmt.Errorf("handles file %s has invalid settings for %s: %w", path, domain, err)
+		}
+
+		_ = provider.SetDomainSettings(context.Background(), domain, settings)
+	}
+
+	for hostname, document := range contents.Documents {
+		handle, err := HostnameToHandle(hostname)
+
+		if err != nil {
+			return nil, fmt.Errorf("handles file %s has a document for an invalid handle: %w", path, err)
+		}
+
+		handle, _ = HandleOnProvidedDomain(context.Background(), provider, handle)
+
+		if canProvide, _ := provider.CanProvideForDomain(context.Background(), handle.Domain); !canProvide {
+			return nil, fmt.Errorf("handles file %s has a document for %s which is not on one of its domains", path, handle)
+		}
+
+		if err := ValidateDIDDocument(handle, document); err != nil {
+			return nil, fmt.Errorf("handles file %s has an invalid document for %s: %w", path, handle, err)
+		}
+
+		_ = provider.SetDIDDocument(context.Background(), handle, document)
+	}
+
+	return provider, nil
+}
+
+func decodeHandlesFile(path string) (handlesFile, error) {
+	reader, err := os.Open(path)
+
+	if err != nil {
+		return handlesFile{}, err
+	}
+
+	defer reader.Close()
+
+	var contents handlesFile
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.NewDecoder(reader).Decode(&contents)
+	case ".yaml", ".yml":
+		err = yaml.NewDecoder(reader).Decode(&contents)
+	case ".csv":
+		contents, err = readHandlesCSV(reader)
+	default:
+		return handlesFile{}, fmt.Errorf("handles file %s must be .json, .yaml, .yml or .csv", path)
+	}
+
+	if err != nil {
+		return handlesFile{}, fmt.Errorf("could not read handles file %s: %w", path, err)
+	}
+
+	return contents, nil
+}
+
+func (contents handlesFile) handles() (MapOfDids, MapOfDomains) {
+	dids := make(MapOfDids)
+
+	for handle, did := range contents.Dids {
+		dids[Hostname(strings.ToLower(handle))] = DecentralizedID(did)
+	}
+
+	domains := make(MapOfDomains)
+
+	for _, domain := range contents.Domains {
+		domains[Domain(strings.ToLower(domain))] = true
+	}
+
+	return dids, domains
+}
+
+// readHandlesCSV reads records of either `handle,did` (a Decentralized ID for
+// a handle) or `domain` (a supported domain). An optional header is skipped.
+func readHandlesCSV(reader io.Reader) (handlesFile, error) {
+	records := csv.NewReader(reader)
+	records.FieldsPerRecord = -1
+	records.TrimLeadingSpace = true
+
+	contents := handlesFile{Dids: make(map[string]string)}
+
+	for line := 1; ; line++ {
+		record, err := records.Read()
+
+		if errors.Is(err, io.EOF) {
+			return contents, nil
+		}
+
+		if err != nil {
+			return handlesFile{}, err
+		}
+
+		if line == 1 && strings.EqualFold(record[0], "handle") {
+			continue
+		}
+
+		switch len(record) {
+		case 1:
+			contents.Domains = append(contents.Domains, record[0])
+		case 2:
+			contents.Dids[record[0]] = record[1]
+		default:
+			return handlesFile{}, fmt.Errorf("line %d must be `handle,did` or `domain`", line)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testFileLogger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+	Level: slog.LevelWarn,
+}))
+
+func writeTestHandlesFile(t *testing.T, path string, contents string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHandlesFileIsReadInSupportedFormats(t *testing.T) {
+	tests := []struct {
+		filename string
+		contents string
+	}{
+		{
+			filename: "handles.json",
+			contents: `{"dids": {"Alice.example.com": "did:plc:example001"}, "domains": ["example.com"]}`,
+		},
+		{
+			filename: "handles.yaml",
+			contents: "dids:\n  alice.example.com: did:plc:example001\ndomains:\n  - example.com\n",
+		},
+		{
+			filename: "handles.csv",
+			contents: "handle,did\nalice.example.com,did:plc:example001\nexample.com\n",
+		},
+	}
+
+	for _, test := range tests {
+		path := filepath.Join(t.TempDir(), test.filename)
+		writeTestHandlesFile(t, path, test.contents)
+
+		dids, domains, err := ReadHandlesFile(path)
+
+		assert.Nil(t, err, "File %s could not be read", test.filename)
+		assert.Equal(t, MapOfDids{"alice.example.com": "did:plc:example001"}, dids)
+		assert.Equal(t, MapOfDomains{"example.com": true}, domains)
+	}
+}
+
+func TestHandlesFileWithUnsupportedExtensionReturnsError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "handles.txt")
+	writeTestHandlesFile(t, path, "alice.example.com")
+
+	_, _, err := ReadHandlesFile(path)
+
+	assert.NotNil(t, err)
+}
+
+func TestFileProviderHasDecentralizedIdForHandle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "handles.json")
+	writeTestHandlesFile(t, path, `{"dids": {"alice.example.com": "did:plc:example001"}, "domains": ["example.com"]}`)
+
+	provider, err := NewFileProvider(path, testFileLogger)
+	assert.Nil(t, err)
+	defer provider.Close()
+
+	did, err := provider.GetDecentralizedIDForHandle(context.Background(), Handle{Domain: "example.com", Username: "alice"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, DecentralizedID("did:plc:example001"), did)
+}
+
+func TestFileProviderReloadsWhenFileChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "handles.json")
+	writeTestHandlesFile(t, path, `{"dids": {"alice.example.com": "did:plc:example001"}, "domains": ["example.com"]}`)
+
+	provider, err := NewFileProvider(path, testFileLogger)
+	assert.Nil(t, err)
+	defer provider.Close()
+
+	writeTestHandlesFile(t, path, `{"dids": {"alice.example.com": "did:plc:example002"}, "domains": ["example.com"]}`)
+
+	assert.Eventually(t, func() bool {
+		did, _ := provider.GetDecentralizedIDForHandle(context.Background(), Handle{Domain: "example.com", Username: "alice"})
+		return did == "did:plc:example002"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestFileProviderKeepsPreviousCopyWhenFileIsInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "handles.json")
+	writeTestHandlesFile(t, path, `{"dids": {"alice.example.com": "did:plc:example001"}, "domains": ["example.com"]}`)
+
+	provider, err := NewFileProvider(path, testFileLogger)
+	assert.Nil(t, err)
+	defer provider.Close()
+
+	writeTestHandlesFile(t, path, `{"dids": `)
+
+	time.Sleep(50 * time.Millisecond)
+
+	did, err := provider.GetDecentralizedIDForHandle(context.Background(), Handle{Domain: "example.com", Username: "alice"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, DecentralizedID("did:plc:example001"), did)
+}
+
+func TestHandlesFileIsReadWithDomainSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "handles.yaml")
+	writeTestHandlesFile(t, path, "domains:\n  - example.com\nsettings:\n  example.com:\n    redirectHandleTemplate: https://example.com/{handle}\n")
+
+	provider, err := LoadHandlesFile(path)
+
+	assert.Nil(t, err)
+
+	settings, _ := provider.GetDomainSettings(context.Background(), "example.com")
+	assert.Equal(t, DomainSettings{RedirectHandleTemplate: "https://example.com/{handle}"}, settings)
+}
+
+func TestHandlesFileRejectsSettingsForUnlistedDomain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "handles.json")
+	writeTestHandlesFile(t, path, `{"domains": ["example.com"], "settings": {"example.net": {"redirectStatus": 302}}}`)
+
+	_, err := LoadHandlesFile(path)
+
+	assert.NotNil(t, err)
+}
+
+func TestHandlesFileIsReadWithDIDDocuments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "handles.yaml")
+	writeTestHandlesFile(t, path, `domains:
+  - example.com
+documents:
+  alice.example.com:
+    id: did:web:alice.example.com
+    alsoKnownAs:
+      - at://alice.example.com
+    service:
+      - id: "#atproto_pds"
+        type: AtprotoPersonalDataServer
+        serviceEndpoint: https://pds.example.com
+`)
+
+	provider, err := LoadHandlesFile(path)
+
+	assert.Nil(t, err)
+
+	document, _ := provider.GetDIDDocument(context.Background(), Handle{Domain: "example.com", Username: "alice"})
+	assert.Equal(t, "https://pds.example.com", document.Service[0].ServiceEndpoint)
+
+	writeTestHandlesFile(t, path, "domains:\n  - example.com\ndocuments:\n  alice.example.com:\n    id: did:web:bob.example.com\n")
+
+	_, err = LoadHandlesFile(path)
+
+	assert.NotNil(t, err)
+}
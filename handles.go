@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 )
@@ -29,6 +30,144 @@ type ProvidesDecentralizedIDs interface {
 	IsHealthy(ctx context.Context) (bool, string)
 }
 
+// HandleOnProvidedDomain splits a handle on the longest domain the provider
+// provides for, so `alice.team.example.com` is `alice.team` on example.com
+// unless team.example.com is provided for. A handle on no provided domain, or
+// with an error, is returned unchanged.
+func HandleOnProvidedDomain(ctx context.Context, provider ProvidesDecentralizedIDs, handle Handle) (Handle, error) {
+	hostname := handle.String()
+
+	for offset := strings.Index(hostname, "."); strings.Contains(hostname[offset+1:], "."); {
+		candidate := Handle{Domain: Domain(hostname[offset+1:]), Username: Username(hostname[:offset])}
+
+		canProvide, err := provider.CanProvideForDomain(ctx, candidate.Domain)
+
+		if err != nil {
+			return handle, err
+		}
+
+		if canProvide {
+			return candidate, nil
+		}
+
+		offset += 1 + strings.Index(hostname[offset+1:], ".")
+	}
+
+	return handle, nil
+}
+
+// DomainPatterns are the domains which provide for a domain, most specific
+// first: the domain itself, then wildcards of the domains above it, such as
+// `*.team.example.com` and `*.example.com` for `eu.team.example.com`.
+func DomainPatterns(domain Domain) []Domain {
+	patterns := []Domain{domain}
+	labels := strings.Split(string(domain), ".")
+
+	for i := 1; i < len(labels)-1; i++ {
+		patterns = append(patterns, Domain("*."+strings.Join(labels[i:], ".")))
+	}
+
+	return patterns
+}
+
+// domainPatternNames are the patterns of a domain as strings, for queries.
+func domainPatternNames(domain Domain) []string {
+	patterns := DomainPatterns(domain)
+	names := make([]string, len(patterns))
+
+	for i, pattern := range patterns {
+		names[i] = string(pattern)
+	}
+
+	return names
+}
+
+// GetValidDecentralizedIDForHandle gets a Decentralized ID from a provider,
+// rejecting it when it is not valid.
+func GetValidDecentralizedIDForHandle(ctx context.Context, provider ProvidesDecentralizedIDs, handle Handle) (DecentralizedID, error) {
+	did, err := provider.GetDecentralizedIDForHandle(ctx, handle)
+
+	if err != nil || did == "" {
+		return did, err
+	}
+
+	if err := ValidateDecentralizedID(did); err != nil {
+		return "", fmt.Errorf("provider returned an invalid Decentralized ID for %s: %w", handle, err)
+	}
+
+	return did, nil
+}
+
+// ManagesDecentralizedIDs is implemented by providers which can be changed
+// through the admin API.
+type ManagesDecentralizedIDs interface {
+	ListDecentralizedIDs(ctx context.Context) (MapOfDids, error)
+	SetDecentralizedIDForHandle(ctx context.Context, handle Handle, did DecentralizedID) error
+	DeleteDecentralizedIDForHandle(ctx context.Context, handle Handle) error
+	ListDomains(ctx context.Context) ([]Domain, error)
+	AddDomain(ctx context.Context, domain Domain) error
+	RemoveDomain(ctx context.Context, domain Domain) error
+}
+
+// UnwrapsProvider is implemented by providers which decorate another provider.
+type UnwrapsProvider interface {
+	Unwrap() ProvidesDecentralizedIDs
+}
+
+// ProviderAs finds the first provider, from the outermost decorator inwards
+// and through combined providers in order, which has the capability T.
+func ProviderAs[T any](provider ProvidesDecentralizedIDs) (T, bool) {
+	var none T
+
+	if provider == nil {
+		return none, false
+	}
+
+	if capable, ok := provider.(T); ok {
+		return capable, true
+	}
+
+	switch wrapper := provider.(type) {
+	case UnwrapsProvider:
+		return ProviderAs[T](wrapper.Unwrap())
+	case UnwrapsProviders:
+		for _, inner := range wrapper.Unwrap() {
+			if capable, ok := ProviderAs[T](inner); ok {
+				return capable, true
+			}
+		}
+	}
+
+	return none, false
+}
+
+// ProvidersAs finds every provider, in the same order as ProviderAs, which has
+// the capability T.
+func ProvidersAs[T any](provider ProvidesDecentralizedIDs) []T {
+	var found []T
+
+	if provider == nil {
+		return found
+	}
+
+	if capable, ok := provider.(T); ok {
+		found = append(found, capable)
+	}
+
+	switch wrapper := provider.(type) {
+	case UnwrapsProvider:
+		found = append(found, ProvidersAs[T](wrapper.Unwrap())...)
+	case UnwrapsProviders:
+		for _, inner := range wrapper.Unwrap() {
+			found = append(found, ProvidersAs[T](inner)...)
+		}
+	}
+
+	return found
+}
+
+var ErrProviderIsReadOnly = errors.New("provider cannot be changed")
+
 type DecentralizedIDNotFoundError struct {
 	handle Handle
 }
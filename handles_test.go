@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -42,3 +43,37 @@ func TestHandleIsRenderedToString(t *testing.T) {
 		)
 	}
 }
+
+func TestDomainPatternsIncludeWildcardsOfDomainsAbove(t *testing.T) {
+	assert.Equal(t, []Domain{"example.com"}, DomainPatterns("example.com"))
+	assert.Equal(t, []Domain{"eu.team.example.com", "*.team.example.com", "*.example.com"}, DomainPatterns("eu.team.example.com"))
+}
+
+func TestHandleIsSplitOnLongestProvidedDomain(t *testing.T) {
+	provider := NewInMemoryProvider(MapOfDids{}, MapOfDomains{
+		"example.com":      true,
+		"team.example.net": true,
+		"*.example.org":    true,
+	})
+
+	tests := []struct {
+		hostname string
+		expected Handle
+	}{
+		{"alice.example.com", Handle{Domain: "example.com", Username: "alice"}},
+		{"alice.team.example.com", Handle{Domain: "example.com", Username: "alice.team"}},
+		{"alice.team.example.net", Handle{Domain: "team.example.net", Username: "alice"}},
+		{"alice.eu.team.example.net", Handle{Domain: "team.example.net", Username: "alice.eu"}},
+		{"alice.eu.team.example.org", Handle{Domain: "eu.team.example.org", Username: "alice"}},
+		{"alice.example.edu", Handle{Domain: "example.edu", Username: "alice"}},
+		{"alice.team.example.edu", Handle{Domain: "team.example.edu", Username: "alice"}},
+	}
+
+	for _, test := range tests {
+		handle, _ := HostnameToHandle(test.hostname)
+		handle, err := HandleOnProvidedDomain(context.Background(), provider, handle)
+
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, handle, test.hostname)
+	}
+}
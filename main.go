@@ -1,11 +1,24 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/miekg/dns"
 	sloggin "github.com/samber/slog-gin"
+	"golang.org/x/crypto/acme/autocert"
 )
 
 func main() {
@@ -15,11 +28,103 @@ func main() {
 		log.Fatal(err)
 	}
 
-	router := gin.New()
+	if len(os.Args) > 1 && os.Args[1] == "check-config" {
+		if err := CheckConfig(config, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
 
-	AddApplicationRoutes(router, config)
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	reloader := NewReloader(config, ReloadConfigFromEnvironment)
+
+	// Everything started here uses whichever provider is current.
+	config.Provider = reloader.Provider()
+
+	var dnsServers []*dns.Server
+
+	if config.DNSAddress != "" {
+		dnsServers = NewDNSServers(config.DNSAddress, NewDNSHandler(config.Provider, config.DNSTTL, config.DNSHostAddresses, config.DNSNameservers, config.Logger))
+
+		for _, server := range dnsServers {
+			go func() {
+				if err := server.ListenAndServe(); err != nil {
+					log.Fatal(err)
+				}
+			}()
+		}
+	}
+
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	go func() {
+		for range hangup {
+			_ = reloader.Reload()
+		}
+	}()
+
+	var manager *autocert.Manager
+
+	if config.ACMEEnabled {
+		if manager, err = NewACMEManager(config); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	server := &http.Server{
+		Addr:    net.JoinHostPort(config.Host, config.Port),
+		Handler: reloader,
+	}
+
+	if manager != nil {
+		// Answers HTTP-01 challenges, passing every other request to the router.
+		server.Handler = manager.HTTPHandler(reloader)
+	}
 
-	if err := router.Run(net.JoinHostPort(config.Host, config.Port)); err != nil {
+	go func() {
+		if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	servers := []*http.Server{server}
+
+	if config.TLSAddress != "" {
+		var certificates *CertificateDirectory
+
+		if config.TLSCertificatesPath != "" {
+			if certificates, err = NewCertificateDirectory(config.TLSCertificatesPath, config.Logger); err != nil {
+				log.Fatal(err)
+			}
+
+			defer certificates.Close()
+		}
+
+		tlsServer := &http.Server{
+			Addr:      config.TLSAddress,
+			Handler:   reloader,
+			TLSConfig: NewTLSConfig(config.Provider, certificates, manager),
+		}
+
+		go func() {
+			if err := tlsServer.ListenAndServeTLS("", ""); !errors.Is(err, http.ErrServerClosed) {
+				log.Fatal(err)
+			}
+		}()
+
+		servers = append(servers, tlsServer)
+	}
+
+	<-ctx.Done()
+
+	// A second signal stops the server immediately.
+	stop()
+
+	if err := Shutdown(config, servers, dnsServers); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -28,13 +133,128 @@ func AddApplicationRoutes(router *gin.Engine, config Config) {
 	router.Use(sloggin.New(config.Logger))
 	router.Use(gin.Recovery())
 
-	router.GET("/healthz", CheckServerIsHealthy(config.Provider))
+	step := func(name string, handler gin.HandlerFunc) gin.HandlerFunc {
+		return handler
+	}
+
+	if config.TracingEnabled {
+		router.ContextWithFallback = true
+		router.Use(TraceRequests)
+		step = Traced
+	}
+
+	if config.Metrics != nil {
+		router.Use(config.Metrics.RecordRequests)
+		router.GET("/metrics", config.Metrics.Handler())
+	}
+
+	router.GET("/healthz", CheckServerIsHealthy(config.Provider, config.Lifecycle))
 	router.GET("/domainz", CheckServerProvidesForDomain(config.Provider, config.CheckDomainParameter))
+	router.GET("/xrpc/com.atproto.identity.resolveHandle", ResolveHandle(config.Provider))
 
-	router.Use(ParseHandleFromHostname)
-	router.Use(WithHandleResult(config.Provider))
+	if config.AdminToken != "" {
+		admin := router.Group("/admin", RequireBearerToken(config.AdminToken))
 
-	router.GET("/.well-known/atproto-did", VerifyHandle)
+		if manager, ok := ProviderAs[ManagesDecentralizedIDs](config.Provider); ok {
+			AddAdminRoutes(admin, config.Provider, manager)
+		}
 
-	router.NoRoute(RedirectUnmatchedRoute(config.RedirectDIDTemplate, config.RedirectHandleTemplate))
+		if config.Verifier != nil {
+			admin.GET("/verification", ListVerifications(config.Verifier))
+		}
+
+		if config.Reload != nil {
+			admin.POST("/reload", ReloadConfiguration(config.Reload))
+		}
+	}
+
+	router.Use(step("ParseHandleFromHostname", ParseHandleFromHostname(config.Provider)))
+	router.Use(step("WithHandleResult", WithHandleResult(config.Provider)))
+
+	router.GET("/.well-known/atproto-did", step("VerifyHandle", VerifyHandle))
+
+	if documents, ok := ProviderAs[ProvidesDIDDocuments](config.Provider); ok {
+		router.GET("/.well-known/did.json", step("ServeDIDDocument", ServeDIDDocument(documents)))
+	}
+
+	redirect := []gin.HandlerFunc{
+		step("RedirectUnmatchedRoute", RedirectUnmatchedRoute(config.Redirects())),
+	}
+
+	if settings, ok := ProviderAs[ProvidesDomainSettings](config.Provider); ok {
+		redirect = slices.Insert(redirect, 0, step("WithDomainSettings", WithDomainSettings(settings)))
+	}
+
+	router.NoRoute(redirect...)
+}
+
+// CheckConfig describes a valid configuration and checks the provider is
+// healthy without listening for requests.
+func CheckConfig(config Config, w io.Writer) error {
+	defer func() {
+		if closer, ok := config.Provider.(io.Closer); ok {
+			closer.Close()
+		}
+	}()
+
+	enabled := func(enabled bool) string {
+		if enabled {
+			return "enabled"
+		}
+
+		return "disabled"
+	}
+
+	listening := func(address string) string {
+		if address == "" {
+			return "disabled"
+		}
+
+		return address
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	healthy, explanation := config.Provider.IsHealthy(ctx)
+
+	if config.ConfigFile != "" {
+		fmt.Fprintf(w, "config file: %s\n", config.ConfigFile)
+	}
+
+	fmt.Fprintf(w, "provider:    %s\n", describeProvider(config.Provider))
+	fmt.Fprintf(w, "http:        %s\n", net.JoinHostPort(config.Host, config.Port))
+	fmt.Fprintf(w, "https:       %s\n", listening(config.TLSAddress))
+	fmt.Fprintf(w, "dns:         %s\n", listening(config.DNSAddress))
+	fmt.Fprintf(w, "admin api:   %s\n", enabled(config.AdminToken != ""))
+	fmt.Fprintf(w, "metrics:     %s\n", enabled(config.Metrics != nil))
+	fmt.Fprintf(w, "tracing:     %s\n", enabled(config.TracingEnabled))
+	fmt.Fprintf(w, "health:      %s\n", explanation)
+
+	if !healthy {
+		return fmt.Errorf("provider is not healthy: %s", explanation)
+	}
+
+	return nil
+}
+
+// describeProvider names a provider and the providers it decorates or
+// combines, such as `CachingProvider -> ChainProvider(InMemoryProvider, PostgresHandles)`.
+func describeProvider(provider ProvidesDecentralizedIDs) string {
+	name := strings.TrimPrefix(fmt.Sprintf("%T", provider), "*main.")
+
+	switch wrapper := provider.(type) {
+	case UnwrapsProvider:
+		return name + " -> " + describeProvider(wrapper.Unwrap())
+	case UnwrapsProviders:
+		inner := make([]string, 0, len(wrapper.Unwrap()))
+
+		for _, provider := range wrapper.Unwrap() {
+			inner = append(inner, describeProvider(provider))
+		}
+
+		return name + "(" + strings.Join(inner, ", ") + ")"
+	default:
+		return name
+	}
 }
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -143,3 +146,62 @@ func TestUnknownHandleRedirectsToDestination(t *testing.T) {
 	assert.Equal(t, "https://example.com/register?handle=carol.example.com", url.String())
 
 }
+
+func TestDidEndpointAcceptsHostWithPort(t *testing.T) {
+	router, _ := NewTestEnvironment()
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "https://alice.example.com:8443/.well-known/atproto-did", nil)
+	router.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+}
+
+func TestDidEndpointReturnsRequestErrorForInvalidHandle(t *testing.T) {
+	router, _ := NewTestEnvironment()
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "https://alice.example.local/.well-known/atproto-did", nil)
+	router.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+	assert.Contains(t, res.Body.String(), "top level domain")
+}
+
+func TestDidEndpointReturnsGatewayErrorForInvalidDid(t *testing.T) {
+	router, provider := NewTestEnvironment()
+
+	_ = provider.SetDecentralizedIDForHandle(context.Background(), Handle{Domain: "example.com", Username: "carol"}, "not-a-did")
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "https://carol.example.com/.well-known/atproto-did", nil)
+	router.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusBadGateway, res.Code)
+}
+
+func TestCheckConfigDescribesConfigurationAndHealth(t *testing.T) {
+	provider := NewInMemoryProvider(MapOfDids{}, MapOfDomains{"example.com": true})
+	output := new(bytes.Buffer)
+
+	err := CheckConfig(Config{
+		Provider:   NewCachingProvider(provider, 10, time.Minute, time.Minute, time.Minute),
+		Host:       "localhost",
+		Port:       "8080",
+		AdminToken: "secret",
+	}, output)
+
+	assert.NoError(t, err)
+	assert.Contains(t, output.String(), "CachingProvider -> InMemoryProvider")
+	assert.Contains(t, output.String(), "localhost:8080")
+	assert.Contains(t, output.String(), "admin api:   enabled")
+}
+
+func TestCheckConfigFailsWhenProviderIsUnhealthy(t *testing.T) {
+	testProviderForRouter.SetHealthy(false)
+	defer testProviderForRouter.SetHealthy(true)
+
+	err := CheckConfig(Config{Provider: testProviderForRouter}, new(bytes.Buffer))
+
+	assert.Error(t, err)
+}
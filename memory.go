@@ -3,6 +3,8 @@ package main
 import (
 	"context"
 	"fmt"
+	"slices"
+	"sync"
 )
 
 type MapOfDids = map[Hostname]DecentralizedID
@@ -10,13 +12,22 @@ type MapOfDids = map[Hostname]DecentralizedID
 type MapOfDomains = map[Domain]bool
 
 type InMemoryProvider struct {
+	mutex     sync.RWMutex
 	dids      MapOfDids
 	domains   MapOfDomains
+	settings  MapOfDomainSettings
+	documents MapOfDIDDocuments
 	isHealthy bool
 }
 
 func NewInMemoryProvider(dids MapOfDids, domains MapOfDomains) *InMemoryProvider {
-	return &InMemoryProvider{dids, domains, true}
+	return &InMemoryProvider{
+		dids:      dids,
+		domains:   domains,
+		settings:  make(MapOfDomainSettings),
+		documents: make(MapOfDIDDocuments),
+		isHealthy: true,
+	}
 }
 
 func (memory *InMemoryProvider) GetDecentralizedIDForHandle(ctx context.Context, handle Handle) (DecentralizedID, error) {
@@ -30,16 +41,31 @@ func (memory *InMemoryProvider) GetDecentralizedIDForHandle(ctx context.Context,
 		return "", &CannotGetHandelsFromDomainError{domain: handle.Domain}
 	}
 
+	memory.mutex.RLock()
+	defer memory.mutex.RUnlock()
+
 	did := memory.dids[Hostname(handle.String())]
 
 	return did, nil
 }
 
 func (memory *InMemoryProvider) CanProvideForDomain(ctx context.Context, domain Domain) (bool, error) {
-	return memory.domains[domain], nil
+	memory.mutex.RLock()
+	defer memory.mutex.RUnlock()
+
+	for _, pattern := range DomainPatterns(domain) {
+		if memory.domains[pattern] {
+			return true, nil
+		}
+	}
+
+	return false, nil
 }
 
 func (memory *InMemoryProvider) IsHealthy(ctx context.Context) (bool, string) {
+	memory.mutex.RLock()
+	defer memory.mutex.RUnlock()
+
 	if memory.isHealthy {
 		return true, fmt.Sprintf("Available with %d handles for %d domains", len(memory.dids), len(memory.domains))
 	}
@@ -48,5 +74,132 @@ func (memory *InMemoryProvider) IsHealthy(ctx context.Context) (bool, string) {
 }
 
 func (memory *InMemoryProvider) SetHealthy(isHealthy bool) {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
 	memory.isHealthy = isHealthy
 }
+
+func (memory *InMemoryProvider) ListDecentralizedIDs(ctx context.Context) (MapOfDids, error) {
+	memory.mutex.RLock()
+	defer memory.mutex.RUnlock()
+
+	dids := make(MapOfDids, len(memory.dids))
+
+	for hostname, did := range memory.dids {
+		dids[hostname] = did
+	}
+
+	return dids, nil
+}
+
+func (memory *InMemoryProvider) SetDecentralizedIDForHandle(ctx context.Context, handle Handle, did DecentralizedID) error {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	memory.dids[Hostname(handle.String())] = did
+
+	return nil
+}
+
+func (memory *InMemoryProvider) DeleteDecentralizedIDForHandle(ctx context.Context, handle Handle) error {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	delete(memory.dids, Hostname(handle.String()))
+
+	return nil
+}
+
+func (memory *InMemoryProvider) ListDomains(ctx context.Context) ([]Domain, error) {
+	memory.mutex.RLock()
+	defer memory.mutex.RUnlock()
+
+	domains := make([]Domain, 0, len(memory.domains))
+
+	for domain, provided := range memory.domains {
+		if provided {
+			domains = append(domains, domain)
+		}
+	}
+
+	slices.Sort(domains)
+
+	return domains, nil
+}
+
+func (memory *InMemoryProvider) AddDomain(ctx context.Context, domain Domain) error {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	memory.domains[domain] = true
+
+	return nil
+}
+
+func (memory *InMemoryProvider) RemoveDomain(ctx context.Context, domain Domain) error {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	delete(memory.domains, domain)
+	delete(memory.settings, domain)
+
+	return nil
+}
+
+func (memory *InMemoryProvider) GetDomainSettings(ctx context.Context, domain Domain) (DomainSettings, error) {
+	memory.mutex.RLock()
+	defer memory.mutex.RUnlock()
+
+	for _, pattern := range DomainPatterns(domain) {
+		if settings, ok := memory.settings[pattern]; ok {
+			return settings, nil
+		}
+	}
+
+	return DomainSettings{}, nil
+}
+
+func (memory *InMemoryProvider) SetDomainSettings(ctx context.Context, domain Domain, settings DomainSettings) error {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	if settings.IsEmpty() {
+		delete(memory.settings, domain)
+	} else {
+		memory.settings[domain] = settings
+	}
+
+	return nil
+}
+
+func (memory *InMemoryProvider) GetDIDDocument(ctx context.Context, handle Handle) (*DIDDocument, error) {
+	memory.mutex.RLock()
+	defer memory.mutex.RUnlock()
+
+	document, ok := memory.documents[Hostname(handle.String())]
+
+	if !ok {
+		return nil, nil
+	}
+
+	return &document, nil
+}
+
+func (memory *InMemoryProvider) SetDIDDocument(ctx context.Context, handle Handle, document DIDDocument) error {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	memory.documents[Hostname(handle.String())] = document
+
+	return nil
+}
+
+func (memory *InMemoryProvider) DeleteDIDDocument(ctx context.Context, handle Handle) error {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	delete(memory.documents, Hostname(handle.String()))
+
+	return nil
+}
@@ -51,3 +51,36 @@ func TestMemoryCanBeSetUnhealthy(t *testing.T) {
 
 	assert.False(t, healthy)
 }
+
+func TestMemoryProviderProvidesForWildcardDomains(t *testing.T) {
+	provider := NewInMemoryProvider(MapOfDids{"alice.eu.team.example.com": "did:plc:example001"}, MapOfDomains{"*.example.com": true})
+
+	for domain, expected := range map[Domain]bool{"team.example.com": true, "eu.team.example.com": true, "example.com": false} {
+		canProvide, err := provider.CanProvideForDomain(context.Background(), domain)
+
+		assert.Nil(t, err)
+		assert.Equal(t, expected, canProvide, domain)
+	}
+
+	did, err := provider.GetDecentralizedIDForHandle(context.Background(), Handle{Domain: "eu.team.example.com", Username: "alice"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, DecentralizedID("did:plc:example001"), did)
+}
+
+func TestMemoryProviderUsesSettingsOfMostSpecificWildcard(t *testing.T) {
+	provider := NewInMemoryProvider(MapOfDids{}, MapOfDomains{"*.example.com": true, "*.team.example.com": true})
+	ctx := context.Background()
+
+	_ = provider.SetDomainSettings(ctx, "*.example.com", DomainSettings{RedirectStatus: 301})
+	_ = provider.SetDomainSettings(ctx, "*.team.example.com", DomainSettings{RedirectStatus: 302})
+
+	settings, _ := provider.GetDomainSettings(ctx, "eu.example.com")
+	assert.Equal(t, 301, settings.RedirectStatus)
+
+	settings, _ = provider.GetDomainSettings(ctx, "eu.team.example.com")
+	assert.Equal(t, 302, settings.RedirectStatus)
+
+	settings, _ = provider.GetDomainSettings(ctx, "example.com")
+	assert.True(t, settings.IsEmpty())
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics are kept in their own registry rather than the global one so that
+// every configuration exposes only what it has measured.
+type Metrics struct {
+	registry         *prometheus.Registry
+	pool             *poolCollector
+	requests         *prometheus.CounterVec
+	requestDuration  *prometheus.HistogramVec
+	resolutions      *prometheus.CounterVec
+	providerDuration *prometheus.HistogramVec
+}
+
+func NewMetrics() *Metrics {
+	metrics := &Metrics{
+		registry: prometheus.NewRegistry(),
+		pool:     &poolCollector{},
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "handles_http_requests_total",
+			Help: "HTTP requests by route, method and status.",
+		}, []string{"route", "method", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "handles_http_request_duration_seconds",
+			Help:    "Time taken to respond to HTTP requests by route.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route"}),
+		resolutions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "handles_resolutions_total",
+			Help: "Handles resolved by outcome (found, not_found, unsupported_domain, error).",
+		}, []string{"outcome"}),
+		providerDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "handles_provider_duration_seconds",
+			Help:    "Time taken by the provider by method.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method"}),
+	}
+
+	metrics.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		metrics.requests,
+		metrics.requestDuration,
+		metrics.resolutions,
+		metrics.providerDuration,
+		metrics.pool,
+	)
+
+	return metrics
+}
+
+func (metrics *Metrics) RecordRequests(c *gin.Context) {
+	started := time.Now()
+
+	c.Next()
+
+	route := c.FullPath()
+
+	if route == "" {
+		route = "unmatched"
+	}
+
+	metrics.requests.WithLabelValues(route, c.Request.Method, strconv.Itoa(c.Writer.Status())).Inc()
+	metrics.requestDuration.WithLabelValues(route).Observe(time.Since(started).Seconds())
+}
+
+func (metrics *Metrics) Handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.HandlerFor(metrics.registry, promhttp.HandlerOpts{}))
+}
+
+// ReportsPoolStatistics is implemented by providers which use a pgx pool.
+type ReportsPoolStatistics interface {
+	Stat() *pgxpool.Stat
+}
+
+// MeasureProvider measures a provider, replacing any provider measured before
+// as the source of pool statistics.
+func (metrics *Metrics) MeasureProvider(provider ProvidesDecentralizedIDs) *MeasuredProvider {
+	pool, _ := ProviderAs[ReportsPoolStatistics](provider)
+
+	metrics.pool.set(pool)
+
+	return &MeasuredProvider{provider, metrics}
+}
+
+// MeasuredProvider records how long the provider takes and the outcome of
+// every resolution.
+type MeasuredProvider struct {
+	provider ProvidesDecentralizedIDs
+	metrics  *Metrics
+}
+
+func (measured *MeasuredProvider) GetDecentralizedIDForHandle(ctx context.Context, handle Handle) (DecentralizedID, error) {
+	started := time.Now()
+
+	did, err := measured.provider.GetDecentralizedIDForHandle(ctx, handle)
+
+	measured.metrics.providerDuration.WithLabelValues("GetDecentralizedIDForHandle").Observe(time.Since(started).Seconds())
+	measured.metrics.resolutions.WithLabelValues(ResolutionOutcome(did, err)).Inc()
+
+	return did, err
+}
+
+func (measured *MeasuredProvider) CanProvideForDomain(ctx context.Context, domain Domain) (bool, error) {
+	started := time.Now()
+
+	canProvide, err := measured.provider.CanProvideForDomain(ctx, domain)
+
+	measured.metrics.providerDuration.WithLabelValues("CanProvideForDomain").Observe(time.Since(started).Seconds())
+
+	return canProvide, err
+}
+
+func (measured *MeasuredProvider) IsHealthy(ctx context.Context) (bool, string) {
+	return measured.provider.IsHealthy(ctx)
+}
+
+func (measured *MeasuredProvider) Unwrap() ProvidesDecentralizedIDs {
+	return measured.provider
+}
+
+func (measured *MeasuredProvider) Close() error {
+	if closer, ok := measured.provider.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+func ResolutionOutcome(did DecentralizedID, err error) string {
+	switch {
+	case errors.Is(err, (*CannotGetHandelsFromDomainError)(nil)):
+		return "unsupported_domain"
+	case err != nil:
+		return "error"
+	case did == "":
+		return "not_found"
+	default:
+		return "found"
+	}
+}
+
+var (
+	poolAcquiredConnections = prometheus.NewDesc("handles_postgres_pool_acquired_connections", "Connections currently acquired from the pool.", nil, nil)
+	poolIdleConnections     = prometheus.NewDesc("handles_postgres_pool_idle_connections", "Idle connections in the pool.", nil, nil)
+	poolTotalConnections    = prometheus.NewDesc("handles_postgres_pool_total_connections", "Connections in the pool.", nil, nil)
+	poolMaxConnections      = prometheus.NewDesc("handles_postgres_pool_max_connections", "Maximum size of the pool.", nil, nil)
+	poolAcquires            = prometheus.NewDesc("handles_postgres_pool_acquires_total", "Successful acquires from the pool.", nil, nil)
+	poolAcquireDuration     = prometheus.NewDesc("handles_postgres_pool_acquire_duration_seconds_total", "Time spent acquiring connections from the pool.", nil, nil)
+	poolEmptyAcquires       = prometheus.NewDesc("handles_postgres_pool_empty_acquires_total", "Acquires which waited for a connection because the pool was empty.", nil, nil)
+	poolCanceledAcquires    = prometheus.NewDesc("handles_postgres_pool_canceled_acquires_total", "Acquires cancelled by their context.", nil, nil)
+)
+
+type poolCollector struct {
+	mutex sync.Mutex
+	pool  ReportsPoolStatistics
+}
+
+func (collector *poolCollector) set(pool ReportsPoolStatistics) {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+
+	collector.pool = pool
+}
+
+func (collector *poolCollector) Describe(descriptions chan<- *prometheus.Desc) {
+	for _, description := range []*prometheus.Desc{
+		poolAcquiredConnections,
+		poolIdleConnections,
+		poolTotalConnections,
+		poolMaxConnections,
+		poolAcquires,
+		poolAcquireDuration,
+		poolEmptyAcquires,
+		poolCanceledAcquires,
+	} {
+		descriptions <- description
+	}
+}
+
+func (collector *poolCollector) Collect(metrics chan<- prometheus.Metric) {
+	collector.mutex.Lock()
+	pool := collector.pool
+	collector.mutex.Unlock()
+
+	if pool == nil {
+		return
+	}
+
+	stat := pool.Stat()
+
+	metrics <- prometheus.MustNewConstMetric(poolAcquiredConnections, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	metrics <- prometheus.MustNewConstMetric(poolIdleConnections, prometheus.GaugeValue, float64(stat.IdleConns()))
+	metrics <- prometheus.MustNewConstMetric(poolTotalConnections, prometheus.GaugeValue, float64(stat.TotalConns()))
+	metrics <- prometheus.MustNewConstMetric(poolMaxConnections, prometheus.GaugeValue, float64(stat.MaxConns()))
+	metrics <- prometheus.MustNewConstMetric(poolAcquires, prometheus.CounterValue, float64(stat.AcquireCount()))
+	metrics <- prometheus.MustNewConstMetric(poolAcquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+	metrics <- prometheus.MustNewConstMetric(poolEmptyAcquires, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	metrics <- prometheus.MustNewConstMetric(poolCanceledAcquires, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+}
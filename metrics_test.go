@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func NewTestMetricsEnvironment() *gin.Engine {
+	metrics := NewMetrics()
+
+	router := gin.New()
+	AddApplicationRoutes(router, Config{
+		Provider: metrics.MeasureProvider(NewInMemoryProvider(map[Hostname]DecentralizedID{
+			"alice.example.com": "did:plc:example001",
+		}, map[Domain]bool{
+			"example.com": true,
+		})),
+		Logger:                 testFileLogger,
+		RedirectDIDTemplate:    "https://example.com/profile/{did}",
+		RedirectHandleTemplate: "https://example.com/register?handle={handle}",
+		Metrics:                metrics,
+	})
+
+	return router
+}
+
+func TestMetricsEndpointReportsRequestsAndResolutions(t *testing.T) {
+	router := NewTestMetricsEnvironment()
+
+	for _, host := range []string{"alice.example.com", "bob.example.com", "alice.example.net"} {
+		req, _ := http.NewRequest("GET", "/.well-known/atproto-did", nil)
+		req.Host = host
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/metrics", nil)
+	router.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Contains(t, res.Body.String(), `handles_http_requests_total{method="GET",route="/.well-known/atproto-did",status="200"} 1`)
+	assert.Contains(t, res.Body.String(), `handles_http_requests_total{method="GET",route="/.well-known/atproto-did",status="404"} 1`)
+	assert.Contains(t, res.Body.String(), `handles_http_requests_total{method="GET",route="/.well-known/atproto-did",status="400"} 1`)
+	assert.Contains(t, res.Body.String(), `handles_resolutions_total{outcome="found"} 1`)
+	assert.Contains(t, res.Body.String(), `handles_resolutions_total{outcome="not_found"} 1`)
+	assert.Contains(t, res.Body.String(), `handles_resolutions_total{outcome="unsupported_domain"} 1`)
+	assert.Contains(t, res.Body.String(), `handles_provider_duration_seconds_count{method="GetDecentralizedIDForHandle"} 3`)
+}
+
+func TestMetricsEndpointIsNotAvailableWhenDisabled(t *testing.T) {
+	router, _ := NewTestEnvironment()
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/metrics", nil)
+	req.Host = "alice.example.com"
+	router.ServeHTTP(res, req)
+
+	assert.NotContains(t, res.Body.String(), "handles_http_requests_total")
+}
+
+func TestResolutionOutcome(t *testing.T) {
+	assert.Equal(t, "found", ResolutionOutcome("did:plc:example001", nil))
+	assert.Equal(t, "not_found", ResolutionOutcome("", nil))
+	assert.Equal(t, "unsupported_domain", ResolutionOutcome("", &CannotGetHandelsFromDomainError{domain: "example.net"}))
+	assert.Equal(t, "error", ResolutionOutcome("", errors.New("connection refused")))
+}
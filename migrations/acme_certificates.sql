@@ -0,0 +1,9 @@
+-- Stores certificates issued using ACME and the ACME account key when
+-- `ACME_CACHE_TABLE` is set. Change the table name below when it is not the
+-- default.
+
+create table if not exists acme_certificates (
+  key text primary key,
+  data bytea not null,
+  updated_at timestamptz not null default now()
+);
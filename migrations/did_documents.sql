@@ -0,0 +1,8 @@
+-- Stores did:web documents served from `/.well-known/did.json` when
+-- `DATABASE_TABLE_DOCUMENTS` is set. Change the table name below when it is
+-- not `did_documents`.
+
+create table if not exists did_documents (
+  handle text primary key,
+  document jsonb not null
+);
@@ -0,0 +1,11 @@
+-- Adds optional settings for each domain which override the global redirect
+-- templates (`REDIRECT_DID_TEMPLATE`, `REDIRECT_HANDLE_TEMPLATE`) and redirect
+-- status, and metadata available to templates as `{domain.<key>}`. Change the
+-- table name below when it is not the default.
+
+alter table domains
+  add column if not exists redirect_did_template text,
+  add column if not exists redirect_handle_template text,
+  add column if not exists redirect_status integer
+    check (redirect_status in (301, 302, 303, 307, 308)),
+  add column if not exists metadata jsonb;
@@ -0,0 +1,43 @@
+-- Notifies handles-server of changes to the dids and domains tables so that
+-- cached results are forgotten immediately (`DATABASE_NOTIFY_CHANNEL`).
+--
+-- Payloads are `handle:<handle>`, `domain:<domain>` or `*` (everything). Change
+-- the table and channel names below when they are not the defaults.
+
+create or replace function handles_server_notify_change() returns trigger as $$
+declare
+  channel text := tg_argv[0];
+  kind text := tg_argv[1];
+begin
+  if tg_op = 'TRUNCATE' then
+    perform pg_notify(channel, '*');
+    return null;
+  end if;
+
+  if tg_op in ('UPDATE', 'DELETE') then
+    perform pg_notify(channel, kind || ':' || lower(row_to_json(old) ->> kind));
+  end if;
+
+  if tg_op in ('INSERT', 'UPDATE') then
+    perform pg_notify(channel, kind || ':' || lower(row_to_json(new) ->> kind));
+  end if;
+
+  return null;
+end;
+$$ language plpgsql;
+
+create or replace trigger dids_notify_change
+  after insert or update or delete on dids
+  for each row execute function handles_server_notify_change('handles_changes', 'handle');
+
+create or replace trigger dids_notify_truncate
+  after truncate on dids
+  for each statement execute function handles_server_notify_change('handles_changes', 'handle');
+
+create or replace trigger domains_notify_change
+  after insert or update or delete on domains
+  for each row execute function handles_server_notify_change('handles_changes', 'domain');
+
+create or replace trigger domains_notify_truncate
+  after truncate on domains
+  for each statement execute function handles_server_notify_change('handles_changes', 'domain');
@@ -4,25 +4,50 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type PostgresHandles struct {
-	pool         *pgxpool.Pool
-	didsTable    string
-	domainsTable string
+	pool           *pgxpool.Pool
+	didsTable      string
+	domainsTable   string
+	documentsTable string
+	domainSettings bool
+	domainMetadata bool
+	closing        context.Context
+	close          context.CancelFunc
 }
 
-func NewPostgresHandlesProvider(config *pgxpool.Config, didsTable string, domainsTable string) (*PostgresHandles, error) {
+func NewPostgresHandlesProvider(config *pgxpool.Config, didsTable string, domainsTable string, documentsTable string) (_ *PostgresHandles, err error) {
 	pool, err := pgxpool.NewWithConfig(context.Background(), config)
 
 	if err != nil {
 		return &PostgresHandles{}, err
 	}
 
-	pg := &PostgresHandles{pool, didsTable, domainsTable}
+	closing, close := context.WithCancel(context.Background())
+
+	pg := &PostgresHandles{
+		pool:           pool,
+		didsTable:      didsTable,
+		domainsTable:   domainsTable,
+		documentsTable: documentsTable,
+		closing:        closing,
+		close:          close,
+	}
+
+	// A database which cannot be used is not kept connected.
+	defer func() {
+		if err != nil {
+			pg.Close()
+		}
+	}()
 
 	healthy, status := pg.IsHealthy(context.Background())
 
@@ -40,6 +65,12 @@ func NewPostgresHandlesProvider(config *pgxpool.Config, didsTable string, domain
 		return &PostgresHandles{}, errors.New("cannot access tables")
 	}
 
+	pg.domainSettings, pg.domainMetadata, err = pg.hasDomainSettingsColumns(context.Background())
+
+	if err != nil {
+		return &PostgresHandles{}, err
+	}
+
 	return pg, nil
 }
 
@@ -94,11 +125,11 @@ func (pg *PostgresHandles) CanProvideForDomain(ctx context.Context, domain Domai
 	exists := false
 
 	query := fmt.Sprintf(
-		"select exists(select 1 from %s where domain = $1)",
+		"select exists(select 1 from %s where domain = any($1))",
 		pgx.Identifier{pg.domainsTable}.Sanitize(),
 	)
 
-	err = connection.QueryRow(ctx, query, domain).Scan(&exists)
+	err = connection.QueryRow(ctx, query, domainPatternNames(domain)).Scan(&exists)
 
 	return exists, err
 }
@@ -121,6 +152,261 @@ func (pg *PostgresHandles) IsHealthy(ctx context.Context) (bool, string) {
 	return true, "Connected to database"
 }
 
+func (pg *PostgresHandles) ListDecentralizedIDs(ctx context.Context) (MapOfDids, error) {
+	query := fmt.Sprintf(
+		"select LOWER(handle), did from %s",
+		pgx.Identifier{pg.didsTable}.Sanitize(),
+	)
+
+	rows, err := pg.pool.Query(ctx, query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dids := make(MapOfDids)
+
+	var hostname Hostname
+	var did DecentralizedID
+
+	_, err = pgx.ForEachRow(rows, []any{&hostname, &did}, func() error {
+		dids[hostname] = did
+		return nil
+	})
+
+	return dids, err
+}
+
+func (pg *PostgresHandles) SetDecentralizedIDForHandle(ctx context.Context, handle Handle, did DecentralizedID) error {
+	// Handles are matched case insensitively and the table may not have a
+	// unique constraint, so an existing handle is updated before inserting.
+	return pgx.BeginFunc(ctx, pg.pool, func(tx pgx.Tx) error {
+		update := fmt.Sprintf(
+			"update %s set did = $2 where LOWER(handle) = LOWER($1)",
+			pgx.Identifier{pg.didsTable}.Sanitize(),
+		)
+
+		result, err := tx.Exec(ctx, update, handle.String(), did)
+
+		if err != nil || result.RowsAffected() > 0 {
+			return err
+		}
+
+		insert := fmt.Sprintf(
+			"insert into %s (handle, did) values ($1, $2)",
+			pgx.Identifier{pg.didsTable}.Sanitize(),
+		)
+
+		_, err = tx.Exec(ctx, insert, handle.String(), did)
+
+		return err
+	})
+}
+
+func (pg *PostgresHandles) DeleteDecentralizedIDForHandle(ctx context.Context, handle Handle) error {
+	query := fmt.Sprintf(
+		"delete from %s where LOWER(handle) = LOWER($1)",
+		pgx.Identifier{pg.didsTable}.Sanitize(),
+	)
+
+	_, err := pg.pool.Exec(ctx, query, handle.String())
+
+	return err
+}
+
+func (pg *PostgresHandles) ListDomains(ctx context.Context) ([]Domain, error) {
+	query := fmt.Sprintf(
+		"select domain from %s order by domain",
+		pgx.Identifier{pg.domainsTable}.Sanitize(),
+	)
+
+	rows, err := pg.pool.Query(ctx, query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowTo[Domain])
+}
+
+func (pg *PostgresHandles) AddDomain(ctx context.Context, domain Domain) error {
+	query := fmt.Sprintf(
+		"insert into %[1]s (domain) select $1 where not exists (select 1 from %[1]s where domain = $1)",
+		pgx.Identifier{pg.domainsTable}.Sanitize(),
+	)
+
+	_, err := pg.pool.Exec(ctx, query, domain)
+
+	return err
+}
+
+func (pg *PostgresHandles) RemoveDomain(ctx context.Context, domain Domain) error {
+	query := fmt.Sprintf(
+		"delete from %s where domain = $1",
+		pgx.Identifier{pg.domainsTable}.Sanitize(),
+	)
+
+	_, err := pg.pool.Exec(ctx, query, domain)
+
+	return err
+}
+
+// Close stops listening for changes and waits for connections in use to be
+// returned before closing the pool.
+func (pg *PostgresHandles) Close() error {
+	pg.close()
+	pg.pool.Close()
+
+	return nil
+}
+
+func (pg *PostgresHandles) Stat() *pgxpool.Stat {
+	return pg.pool.Stat()
+}
+
+// hasDomainSettingsColumns checks for the optional columns added to the
+// domains table by `migrations/domain_settings.sql`; the metadata column was
+// added after the other settings.
+func (pg *PostgresHandles) hasDomainSettingsColumns(ctx context.Context) (bool, bool, error) {
+	rows, err := pg.pool.Query(
+		ctx,
+		"select column_name from information_schema.columns where table_schema = any(current_schemas(false)) and table_name = $1 and column_name in ('redirect_did_template', 'redirect_handle_template', 'redirect_status', 'metadata')",
+		pg.domainsTable,
+	)
+
+	if err != nil {
+		return false, false, err
+	}
+
+	columns, err := pgx.CollectRows(rows, pgx.RowTo[string])
+
+	if err != nil {
+		return false, false, err
+	}
+
+	hasSettings := slices.Contains(columns, "redirect_did_template") &&
+		slices.Contains(columns, "redirect_handle_template") &&
+		slices.Contains(columns, "redirect_status")
+	hasMetadata := hasSettings && slices.Contains(columns, "metadata")
+
+	return hasSettings, hasMetadata, nil
+}
+
+func (pg *PostgresHandles) GetDomainSettings(ctx context.Context, domain Domain) (DomainSettings, error) {
+	if !pg.domainSettings {
+		return DomainSettings{}, nil
+	}
+
+	metadata := "null::jsonb"
+
+	if pg.domainMetadata {
+		metadata = "metadata"
+	}
+
+	query := fmt.Sprintf(
+		"select coalesce(redirect_did_template, ''), coalesce(redirect_handle_template, ''), coalesce(redirect_status, 0), %s from %s where domain = any($1) order by array_position($1, domain) limit 1",
+		metadata,
+		pgx.Identifier{pg.domainsTable}.Sanitize(),
+	)
+
+	var settings DomainSettings
+
+	// The settings of the domain itself, or of the most specific wildcard.
+	err := pg.pool.QueryRow(ctx, query, domainPatternNames(domain)).Scan(
+		&settings.RedirectDIDTemplate,
+		&settings.RedirectHandleTemplate,
+		&settings.RedirectStatus,
+		&settings.Metadata,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return DomainSettings{}, nil
+	}
+
+	return settings, err
+}
+
+func (pg *PostgresHandles) SetDomainSettings(ctx context.Context, domain Domain, settings DomainSettings) error {
+	if !pg.domainSettings || (len(settings.Metadata) > 0 && !pg.domainMetadata) {
+		return fmt.Errorf("domains table %s does not have settings columns (see migrations/domain_settings.sql): %w", pg.domainsTable, ErrProviderIsReadOnly)
+	}
+
+	assignments := "redirect_did_template = nullif($2, ''), redirect_handle_template = nullif($3, ''), redirect_status = nullif($4, 0)"
+	arguments := []any{domain, string(settings.RedirectDIDTemplate), string(settings.RedirectHandleTemplate), settings.RedirectStatus}
+
+	if pg.domainMetadata {
+		assignments += ", metadata = $5"
+		arguments = append(arguments, settings.Metadata)
+	}
+
+	query := fmt.Sprintf(
+		"update %s set %s where domain = $1",
+		pgx.Identifier{pg.domainsTable}.Sanitize(),
+		assignments,
+	)
+
+	_, err := pg.pool.Exec(ctx, query, arguments...)
+
+	return err
+}
+
+// GetDIDDocument reads documents from the table created by
+// `migrations/did_documents.sql` when it is configured.
+func (pg *PostgresHandles) GetDIDDocument(ctx context.Context, handle Handle) (*DIDDocument, error) {
+	if pg.documentsTable == "" {
+		return nil, nil
+	}
+
+	query := fmt.Sprintf(
+		"select document from %s where LOWER(handle) = LOWER($1)",
+		pgx.Identifier{pg.documentsTable}.Sanitize(),
+	)
+
+	var document DIDDocument
+
+	err := pg.pool.QueryRow(ctx, query, handle.String()).Scan(&document)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &document, nil
+}
+
+func (pg *PostgresHandles) SetDIDDocument(ctx context.Context, handle Handle, document DIDDocument) error {
+	if pg.documentsTable == "" {
+		return fmt.Errorf("a table of documents (`DATABASE_TABLE_DOCUMENTS`) is not configured: %w", ErrProviderIsReadOnly)
+	}
+
+	query := fmt.Sprintf(
+		"insert into %s (handle, document) values ($1, $2) on conflict (handle) do update set document = excluded.document",
+		pgx.Identifier{pg.documentsTable}.Sanitize(),
+	)
+
+	_, err := pg.pool.Exec(ctx, query, handle.String(), document)
+
+	return err
+}
+
+func (pg *PostgresHandles) DeleteDIDDocument(ctx context.Context, handle Handle) error {
+	if pg.documentsTable == "" {
+		return fmt.Errorf("a table of documents (`DATABASE_TABLE_DOCUMENTS`) is not configured: %w", ErrProviderIsReadOnly)
+	}
+
+	query := fmt.Sprintf(
+		"delete from %s where LOWER(handle) = LOWER($1)",
+		pgx.Identifier{pg.documentsTable}.Sanitize(),
+	)
+
+	_, err := pg.pool.Exec(ctx, query, handle.String())
+
+	return err
+}
+
 func (pg *PostgresHandles) canAccessTables(ctx context.Context) (bool, error) {
 	connection, err := pg.pool.Acquire(ctx)
 
@@ -142,3 +428,87 @@ func (pg *PostgresHandles) canAccessTables(ctx context.Context) (bool, error) {
 
 	return canAccessTables, err
 }
+
+// ListenForChanges evicts handles and domains from a cache as notifications
+// of changes (see migrations/notify_changes.sql) arrive on a channel. Any
+// notifications missed while reconnecting are covered by forgetting everything.
+func (pg *PostgresHandles) ListenForChanges(ctx context.Context, channel string, cache InvalidatesCache, logger *slog.Logger) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stop := context.AfterFunc(pg.closing, cancel)
+	defer stop()
+
+	backoff := time.Second
+
+	for {
+		err := pg.listen(ctx, channel, cache, logger, func() { backoff = time.Second })
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		logger.Error("stopped listening for changes, forgetting cache and reconnecting", "channel", channel, "error", err, "backoff", backoff)
+
+		cache.ForgetAll()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, 30*time.Second)
+	}
+}
+
+func (pg *PostgresHandles) listen(ctx context.Context, channel string, cache InvalidatesCache, logger *slog.Logger, listening func()) error {
+	connection, err := pg.pool.Acquire(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	defer connection.Release()
+
+	if _, err := connection.Exec(ctx, "listen "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		return err
+	}
+
+	listening()
+
+	for {
+		notification, err := connection.Conn().WaitForNotification(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		if err := ApplyChangeNotification(notification.Payload, cache); err != nil {
+			logger.Warn("forgetting cache after unrecognised change notification", "channel", channel, "error", err)
+			cache.ForgetAll()
+		}
+	}
+}
+
+// ApplyChangeNotification evicts the handle or domain named in a notification
+// payload of `handle:<handle>`, `domain:<domain>` or `*` for everything.
+func ApplyChangeNotification(payload string, cache InvalidatesCache) error {
+	if payload == "*" {
+		cache.ForgetAll()
+		return nil
+	}
+
+	kind, value, _ := strings.Cut(payload, ":")
+
+	switch kind {
+	case "handle":
+		cache.ForgetHandle(Hostname(value))
+	case "domain":
+		cache.ForgetDomain(Domain(value))
+	default:
+		return fmt.Errorf("change notification %q is not `handle:<handle>`, `domain:<domain>` or `*`", payload)
+	}
+
+	return nil
+}
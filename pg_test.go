@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangeNotificationsEvictFromCache(t *testing.T) {
+	tests := []struct {
+		payload         string
+		expectedHandles int
+		expectedDomains int
+	}{
+		{payload: "handle:alice.example.com", expectedHandles: 1, expectedDomains: 1},
+		{payload: "handle:ALICE.example.com", expectedHandles: 1, expectedDomains: 1},
+		{payload: "handle:carol.example.com", expectedHandles: 2, expectedDomains: 1},
+		{payload: "domain:example.com", expectedHandles: 0, expectedDomains: 0},
+		{payload: "*", expectedHandles: 0, expectedDomains: 0},
+	}
+
+	for _, test := range tests {
+		cache := NewCachingProvider(newTestCountingProvider(), 10, time.Minute, time.Minute, time.Minute)
+
+		_, _ = cache.CanProvideForDomain(context.Background(), "example.com")
+		_, _ = cache.GetDecentralizedIDForHandle(context.Background(), Handle{Domain: "example.com", Username: "alice"})
+		_, _ = cache.GetDecentralizedIDForHandle(context.Background(), Handle{Domain: "example.com", Username: "bob"})
+
+		err := ApplyChangeNotification(test.payload, cache)
+
+		assert.Nil(t, err)
+		assert.Equal(t, test.expectedHandles, cache.handles.len(), "Handles remaining after %s", test.payload)
+		assert.Equal(t, test.expectedDomains, cache.domains.len(), "Domains remaining after %s", test.payload)
+	}
+}
+
+func TestUnrecognisedChangeNotificationReturnsError(t *testing.T) {
+	cache := NewCachingProvider(newTestCountingProvider(), 10, time.Minute, time.Minute, time.Minute)
+
+	assert.NotNil(t, ApplyChangeNotification("alice.example.com", cache))
+	assert.NotNil(t, ApplyChangeNotification("user:alice.example.com", cache))
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SwappableProvider passes calls to whichever provider it currently holds so
+// that long lived users, like the DNS and TLS servers, see reloaded providers.
+type SwappableProvider struct {
+	current atomic.Pointer[ProvidesDecentralizedIDs]
+}
+
+func NewSwappableProvider(provider ProvidesDecentralizedIDs) *SwappableProvider {
+	swappable := &SwappableProvider{}
+	swappable.Swap(provider)
+
+	return swappable
+}
+
+// Swap replaces the provider, returning the provider it replaced.
+func (swappable *SwappableProvider) Swap(provider ProvidesDecentralizedIDs) ProvidesDecentralizedIDs {
+	if previous := swappable.current.Swap(&provider); previous != nil {
+		return *previous
+	}
+
+	return nil
+}
+
+func (swappable *SwappableProvider) GetDecentralizedIDForHandle(ctx context.Context, handle Handle) (DecentralizedID, error) {
+	return swappable.Unwrap().GetDecentralizedIDForHandle(ctx, handle)
+}
+
+func (swappable *SwappableProvider) CanProvideForDomain(ctx context.Context, domain Domain) (bool, error) {
+	return swappable.Unwrap().CanProvideForDomain(ctx, domain)
+}
+
+func (swappable *SwappableProvider) IsHealthy(ctx context.Context) (bool, string) {
+	return swappable.Unwrap().IsHealthy(ctx)
+}
+
+func (swappable *SwappableProvider) Unwrap() ProvidesDecentralizedIDs {
+	return *swappable.current.Load()
+}
+
+func (swappable *SwappableProvider) Close() error {
+	if closer, ok := swappable.Unwrap().(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+// Reloader serves requests using the routes of the current configuration and
+// replaces the configuration when reloaded, keeping the running configuration
+// when the new configuration is not valid.
+type Reloader struct {
+	load     func(running Config) (Config, error)
+	provider *SwappableProvider
+	handler  atomic.Pointer[gin.Engine]
+
+	mutex  sync.Mutex
+	config Config
+}
+
+func NewReloader(config Config, load func(running Config) (Config, error)) *Reloader {
+	reloader := &Reloader{load: load, provider: NewSwappableProvider(config.Provider)}
+	reloader.use(config)
+
+	return reloader
+}
+
+func (reloader *Reloader) use(config Config) {
+	config.Reload = reloader.Reload
+
+	router := gin.New()
+	AddApplicationRoutes(router, config)
+
+	reloader.config = config
+	reloader.handler.Store(router)
+}
+
+// Provider always passes calls to the provider of the current configuration.
+func (reloader *Reloader) Provider() *SwappableProvider {
+	return reloader.provider
+}
+
+func (reloader *Reloader) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	reloader.handler.Load().ServeHTTP(w, r)
+}
+
+// Reload reads the configuration again and swaps it in. The previous provider
+// is closed once requests which may still be using it have had time to finish.
+func (reloader *Reloader) Reload() error {
+	reloader.mutex.Lock()
+	defer reloader.mutex.Unlock()
+
+	running := reloader.config
+
+	config, err := reloader.load(running)
+
+	if err != nil {
+		running.Logger.Error("could not reload configuration, continuing with running configuration", "error", err)
+		return err
+	}
+
+	reloader.use(config)
+
+	previous := reloader.provider.Swap(config.Provider)
+
+	if closer, ok := previous.(io.Closer); ok {
+		time.AfterFunc(running.ShutdownTimeout, func() {
+			if err := closer.Close(); err != nil {
+				config.Logger.Error("could not close previous provider", "error", err)
+			}
+		})
+	}
+
+	config.Logger.Info("reloaded configuration")
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+type testConfigLoader struct {
+	configs []Config
+	loaded  atomic.Int32
+}
+
+func (loader *testConfigLoader) load(running Config) (Config, error) {
+	index := int(loader.loaded.Add(1))
+
+	if index >= len(loader.configs) {
+		return Config{}, errors.New("`REDIRECT_DID_TEMPLATE` is not valid")
+	}
+
+	return loader.configs[index], nil
+}
+
+func newTestReloadConfig(template URLTemplate, provider ProvidesDecentralizedIDs) Config {
+	return Config{
+		Provider:               provider,
+		Logger:                 testFileLogger,
+		RedirectDIDTemplate:    template,
+		RedirectHandleTemplate: "https://example.com/register?handle={handle}",
+		AdminToken:             "secret",
+	}
+}
+
+func redirectFor(handler http.Handler, host string) string {
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Host = host
+	handler.ServeHTTP(res, req)
+
+	return res.Header().Get("Location")
+}
+
+func TestReloadSwapsTemplatesAndProvider(t *testing.T) {
+	first := &closeableProvider{ProvidesDecentralizedIDs: NewInMemoryProvider(MapOfDids{
+		"alice.example.com": "did:plc:example001",
+	}, MapOfDomains{"example.com": true})}
+
+	second := NewInMemoryProvider(MapOfDids{
+		"alice.example.com": "did:plc:example002",
+	}, MapOfDomains{"example.com": true})
+
+	loader := &testConfigLoader{configs: []Config{
+		newTestReloadConfig("https://example.com/first/{did}", first),
+		newTestReloadConfig("https://example.com/second/{did}", second),
+	}}
+
+	reloader := NewReloader(loader.configs[0], loader.load)
+
+	assert.Equal(t, "https://example.com/first/did:plc:example001", redirectFor(reloader, "alice.example.com"))
+
+	assert.NoError(t, reloader.Reload())
+
+	assert.Equal(t, "https://example.com/second/did:plc:example002", redirectFor(reloader, "alice.example.com"))
+
+	did, _ := reloader.Provider().GetDecentralizedIDForHandle(context.Background(), Handle{Domain: "example.com", Username: "alice"})
+	assert.Equal(t, DecentralizedID("did:plc:example002"), did)
+
+	assert.Eventually(t, func() bool { return first.closed.Load() }, time.Second, 10*time.Millisecond)
+}
+
+func TestReloadSwapsProviderOfDNSServer(t *testing.T) {
+	first := &closeableProvider{ProvidesDecentralizedIDs: NewInMemoryProvider(MapOfDids{
+		"alice.example.com": "did:plc:example001",
+	}, MapOfDomains{"example.com": true})}
+
+	second := NewInMemoryProvider(MapOfDids{
+		"alice.example.com": "did:plc:example002",
+	}, MapOfDomains{"example.com": true})
+
+	loader := &testConfigLoader{configs: []Config{
+		newTestReloadConfig("https://example.com/first/{did}", first),
+		newTestReloadConfig("https://example.com/second/{did}", second),
+	}}
+
+	reloader := NewReloader(loader.configs[0], loader.load)
+	address := newTestDNSServerFor(t, reloader.Provider())
+
+	assert.NoError(t, reloader.Reload())
+	assert.Eventually(t, func() bool { return first.closed.Load() }, time.Second, 10*time.Millisecond)
+
+	response := queryTestDNSServer(t, address, "_atproto.alice.example.com.", dns.TypeTXT)
+
+	assert.Equal(t, dns.RcodeSuccess, response.Rcode)
+	assert.Len(t, response.Answer, 1)
+	assert.Equal(t, []string{"did=did:plc:example002"}, response.Answer[0].(*dns.TXT).Txt)
+}
+
+func TestReloadKeepsRunningConfigurationWhenNewConfigurationIsNotValid(t *testing.T) {
+	provider := NewInMemoryProvider(MapOfDids{
+		"alice.example.com": "did:plc:example001",
+	}, MapOfDomains{"example.com": true})
+
+	loader := &testConfigLoader{configs: []Config{
+		newTestReloadConfig("https://example.com/first/{did}", provider),
+	}}
+
+	reloader := NewReloader(loader.configs[0], loader.load)
+
+	assert.Error(t, reloader.Reload())
+	assert.Equal(t, "https://example.com/first/did:plc:example001", redirectFor(reloader, "alice.example.com"))
+}
+
+func TestReloadEndpointReloadsConfiguration(t *testing.T) {
+	provider := newTestAdminProvider()
+
+	loader := &testConfigLoader{configs: []Config{
+		newTestReloadConfig("https://example.com/first/{did}", provider),
+		newTestReloadConfig("https://example.com/second/{did}", provider),
+	}}
+
+	reloader := NewReloader(loader.configs[0], loader.load)
+
+	res := adminRequest(reloader.handler.Load(), "POST", "/admin/reload", "")
+	assert.Equal(t, http.StatusNoContent, res.Code)
+
+	res = adminRequest(reloader.handler.Load(), "POST", "/admin/reload", "")
+	assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+	assert.Contains(t, res.Body.String(), "REDIRECT_DID_TEMPLATE")
+
+	assert.Equal(t, int32(2), loader.loaded.Load())
+}
+
+func TestFailedReloadClosesProvider(t *testing.T) {
+	if _, err := os.ReadDir("/proc/self/fd"); err != nil {
+		t.Skip("open files cannot be counted")
+	}
+
+	t.Setenv("DID_PROVIDER", "sqlite")
+	t.Setenv("SQLITE_PATH", filepath.Join(t.TempDir(), "handles.db"))
+	t.Setenv("CACHE_TTL", "x")
+
+	openFiles := func() int {
+		files, _ := os.ReadDir("/proc/self/fd")
+		return len(files)
+	}
+
+	before := openFiles()
+
+	for range 5 {
+		_, err := ReloadConfigFromEnvironment(Config{Lifecycle: &Lifecycle{}})
+		assert.Error(t, err)
+	}
+
+	assert.Equal(t, before, openFiles())
+}
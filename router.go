@@ -9,8 +9,14 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func CheckServerIsHealthy(provider ProvidesDecentralizedIDs) gin.HandlerFunc {
+func CheckServerIsHealthy(provider ProvidesDecentralizedIDs, lifecycle *Lifecycle) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if lifecycle.IsDraining() {
+			c.String(http.StatusServiceUnavailable, "Shutting down")
+			c.Abort()
+			return
+		}
+
 		healthy, explanation := provider.IsHealthy(c)
 		if !healthy {
 			_ = c.AbortWithError(http.StatusInternalServerError, errors.New(explanation))
@@ -24,17 +30,29 @@ type Result struct {
 	DecentralizedID    DecentralizedID
 }
 
-func ParseHandleFromHostname(c *gin.Context) {
-	handle, err := HostnameToHandle(c.Request.Host)
+// ParseHandleFromHostname sets the handle of the request's host, on the longest
+// domain the provider provides for.
+func ParseHandleFromHostname(provider ProvidesDecentralizedIDs) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		handle, err := HostnameToHandle(HostToHostname(c.Request.Host))
 
-	if err != nil {
-		_ = c.AbortWithError(http.StatusBadRequest, err)
-		return
-	}
+		if err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			c.Abort()
+			return
+		}
 
-	c.Set("handle", handle)
+		handle, err = HandleOnProvidedDomain(c, provider, handle)
 
-	c.Next()
+		if err != nil {
+			_ = c.AbortWithError(http.StatusBadGateway, err)
+			return
+		}
+
+		c.Set("handle", handle)
+
+		c.Next()
+	}
 }
 
 func CheckServerProvidesForDomain(provider ProvidesDecentralizedIDs, handleParameter string) gin.HandlerFunc {
@@ -42,7 +60,15 @@ func CheckServerProvidesForDomain(provider ProvidesDecentralizedIDs, handleParam
 		handle, err := HostnameToHandle(strings.ToLower(c.Query(handleParameter)))
 
 		if err != nil {
-			_ = c.AbortWithError(http.StatusBadRequest, err)
+			c.String(http.StatusBadRequest, err.Error())
+			c.Abort()
+			return
+		}
+
+		handle, err = HandleOnProvidedDomain(c, provider, handle)
+
+		if err != nil {
+			_ = c.AbortWithError(http.StatusInternalServerError, err)
 			return
 		}
 
@@ -66,7 +92,7 @@ func WithHandleResult(provider ProvidesDecentralizedIDs) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		handle := c.MustGet("handle").(Handle)
 
-		did, err := provider.GetDecentralizedIDForHandle(c, handle)
+		did, err := GetValidDecentralizedIDForHandle(c, provider, handle)
 
 		if errors.Is(err, (*CannotGetHandelsFromDomainError)(nil)) {
 			c.String(
@@ -103,25 +129,139 @@ func VerifyHandle(c *gin.Context) {
 	c.String(http.StatusOK, string(result.DecentralizedID))
 }
 
-func RedirectUnmatchedRoute(redirectDid URLTemplate, redirectHandle URLTemplate) gin.HandlerFunc {
+// MethodResponse is how requests other than GET are answered by redirects.
+type MethodResponse string
+
+const (
+	// MethodRedirect redirects as GET requests are redirected.
+	MethodRedirect MethodResponse = "redirect"
+	// MethodPreserve redirects using 307 or 308 so clients repeat the method.
+	MethodPreserve MethodResponse = "preserve"
+	// MethodStatus responds 200 or 404 without redirecting.
+	MethodStatus MethodResponse = "status"
+	// MethodReject responds 405 Method Not Allowed.
+	MethodReject MethodResponse = "reject"
+)
+
+func (response *MethodResponse) UnmarshalText(text []byte) error {
+	switch MethodResponse(text) {
+	case MethodRedirect, MethodPreserve, MethodStatus, MethodReject:
+		*response = MethodResponse(text)
+		return nil
+	default:
+		return fmt.Errorf("%q is not redirect, preserve, status or reject", text)
+	}
+}
+
+// Redirects configures how requests for routes which aren't served are
+// redirected, before settings for the domain are applied.
+type Redirects struct {
+	DIDTemplate    URLTemplate
+	HandleTemplate URLTemplate
+	DIDStatus      int
+	HandleStatus   int
+	// NotFoundPage answers requests for handles without a Decentralized ID
+	// with a 404 page instead of redirecting.
+	NotFoundPage bool
+	Head         MethodResponse
+	OtherMethods MethodResponse
+}
+
+func (redirects Redirects) methodResponse(method string) MethodResponse {
+	switch method {
+	case http.MethodGet:
+		return MethodRedirect
+	case http.MethodHead:
+		return redirects.Head
+	default:
+		return redirects.OtherMethods
+	}
+}
+
+func (redirects Redirects) allowedMethods() string {
+	allowed := []string{http.MethodGet}
+
+	if redirects.Head != MethodReject {
+		allowed = append(allowed, http.MethodHead)
+	}
+
+	return strings.Join(allowed, ", ")
+}
+
+// preserveMethod changes a redirect status to the equivalent which requires
+// clients to repeat the request's method and body.
+func preserveMethod(status int) int {
+	if status == http.StatusMovedPermanently || status == http.StatusPermanentRedirect {
+		return http.StatusPermanentRedirect
+	}
+
+	return http.StatusTemporaryRedirect
+}
+
+func RedirectUnmatchedRoute(redirects Redirects) gin.HandlerFunc {
+	if redirects.DIDStatus == 0 {
+		redirects.DIDStatus = http.StatusTemporaryRedirect
+	}
+
+	if redirects.HandleStatus == 0 {
+		redirects.HandleStatus = http.StatusTemporaryRedirect
+	}
+
+	defaults := DomainSettings{
+		RedirectDIDTemplate:    redirects.DIDTemplate,
+		RedirectHandleTemplate: redirects.HandleTemplate,
+	}
+
 	return func(c *gin.Context) {
 		result := c.MustGet("result").(Result)
+		handle := c.MustGet("handle").(Handle)
+		settings := defaults
 
-		if result.HasDecentralizedID {
-			c.Redirect(http.StatusTemporaryRedirect, URLFromTemplate(
-				redirectDid,
-				c.Request,
-				c.MustGet("handle").(Handle),
-				result.DecentralizedID,
-			))
+		if domainSettings, ok := c.Get("settings"); ok {
+			settings = domainSettings.(DomainSettings).Or(defaults)
+		}
+
+		response := redirects.methodResponse(c.Request.Method)
+
+		if response == MethodReject {
+			c.Header("Allow", redirects.allowedMethods())
+			c.String(http.StatusMethodNotAllowed, "Method %s is not allowed", c.Request.Method)
+			return
+		}
+
+		if response == MethodStatus {
+			if result.HasDecentralizedID {
+				c.Status(http.StatusOK)
+			} else {
+				c.Status(http.StatusNotFound)
+			}
+			return
+		}
+
+		if !result.HasDecentralizedID && redirects.NotFoundPage {
+			c.String(http.StatusNotFound, DecentralizedIDNotFoundError{handle}.Error())
 			return
 		}
 
-		c.Redirect(http.StatusTemporaryRedirect, URLFromTemplate(
-			redirectHandle,
-			c.Request,
-			c.MustGet("handle").(Handle),
-			DecentralizedID(""),
-		))
+		template, status := settings.RedirectHandleTemplate, redirects.HandleStatus
+
+		if result.HasDecentralizedID {
+			template, status = settings.RedirectDIDTemplate, redirects.DIDStatus
+		}
+
+		if settings.RedirectStatus != 0 {
+			status = settings.RedirectStatus
+		}
+
+		if response == MethodPreserve {
+			status = preserveMethod(status)
+		}
+
+		c.Redirect(status, URLFromTemplate(template, TemplateValues{
+			Request:  c.Request,
+			Handle:   handle,
+			DID:      result.DecentralizedID,
+			Metadata: settings.Metadata,
+		}))
 	}
 }
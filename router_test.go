@@ -16,7 +16,7 @@ func TestValidHandleIsAddedToRequestContext(t *testing.T) {
 	req.Host = "alice.example.com"
 	ctx.Request = req
 
-	ParseHandleFromHostname(ctx)
+	ParseHandleFromHostname(testProviderForRouter)(ctx)
 
 	assert.Equal(t, ctx.MustGet("handle"), Handle{
 		Domain:   "example.com",
@@ -32,7 +32,7 @@ func TestInvalidHostnameCausesBadRequest(t *testing.T) {
 	req.Host = "alice"
 	ctx.Request = req
 
-	ParseHandleFromHostname(ctx)
+	ParseHandleFromHostname(testProviderForRouter)(ctx)
 
 	assert.Equal(t, http.StatusBadRequest, res.Code)
 }
@@ -100,10 +100,10 @@ func TestRedirectsUnmatchedRouteWithDecentralizedID(t *testing.T) {
 		DecentralizedID:    DecentralizedID("did:plc:example"),
 	})
 
-	RedirectUnmatchedRoute(
-		URLTemplate("https://example.com/{did}"),
-		URLTemplate("https://example.com/{handle}"),
-	)(ctx)
+	RedirectUnmatchedRoute(Redirects{
+		DIDTemplate:    URLTemplate("https://example.com/{did}"),
+		HandleTemplate: URLTemplate("https://example.com/{handle}"),
+	})(ctx)
 
 	url, _ := res.Result().Location()
 
@@ -128,10 +128,10 @@ func TestRedirectsUnmatchedRouteWithoutDecentralizedID(t *testing.T) {
 		HasDecentralizedID: false,
 	})
 
-	RedirectUnmatchedRoute(
-		URLTemplate("https://example.com/{did}"),
-		URLTemplate("https://example.com/?from={handle}"),
-	)(ctx)
+	RedirectUnmatchedRoute(Redirects{
+		DIDTemplate:    URLTemplate("https://example.com/{did}"),
+		HandleTemplate: URLTemplate("https://example.com/?from={handle}"),
+	})(ctx)
 
 	url, _ := res.Result().Location()
 
@@ -149,7 +149,7 @@ func TestServerIsHealthyWhenProviderIsHealthy(t *testing.T) {
 
 	testProviderForRouter.SetHealthy(true)
 
-	CheckServerIsHealthy(testProviderForRouter)(ctx)
+	CheckServerIsHealthy(testProviderForRouter, &Lifecycle{})(ctx)
 
 	assert.Equal(t, http.StatusOK, res.Code)
 }
@@ -164,7 +164,101 @@ func TestServerIsUnhealthyWhenProviderIsUnhealthy(t *testing.T) {
 
 	testProviderForRouter.SetHealthy(false)
 
-	CheckServerIsHealthy(testProviderForRouter)(ctx)
+	CheckServerIsHealthy(testProviderForRouter, &Lifecycle{})(ctx)
 
 	assert.Equal(t, http.StatusInternalServerError, res.Code)
 }
+
+func TestServerIsUnhealthyWhenDraining(t *testing.T) {
+	res := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(res)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	ctx.Request = req
+
+	testProviderForRouter.SetHealthy(true)
+
+	lifecycle := &Lifecycle{}
+	lifecycle.Drain()
+
+	CheckServerIsHealthy(testProviderForRouter, lifecycle)(ctx)
+
+	assert.Equal(t, http.StatusServiceUnavailable, res.Code)
+}
+
+func TestRedirectsUnmatchedRouteUsingConfiguredResponses(t *testing.T) {
+	tests := []struct {
+		name             string
+		redirects        Redirects
+		method           string
+		found            bool
+		expectedStatus   int
+		expectedLocation string
+	}{
+		{"found status", Redirects{DIDStatus: http.StatusMovedPermanently}, "GET", true, http.StatusMovedPermanently, "https://example.com/did:plc:example"},
+		{"not found status", Redirects{HandleStatus: http.StatusFound}, "GET", false, http.StatusFound, "https://example.com/?from=alice.example.com"},
+		{"not found page", Redirects{NotFoundPage: true}, "GET", false, http.StatusNotFound, ""},
+		{"found with not found page", Redirects{NotFoundPage: true}, "GET", true, http.StatusTemporaryRedirect, "https://example.com/did:plc:example"},
+		{"head redirect", Redirects{}, "HEAD", true, http.StatusTemporaryRedirect, "https://example.com/did:plc:example"},
+		{"head status found", Redirects{Head: MethodStatus}, "HEAD", true, http.StatusOK, ""},
+		{"head status not found", Redirects{Head: MethodStatus}, "HEAD", false, http.StatusNotFound, ""},
+		{"post preserve permanent", Redirects{DIDStatus: http.StatusMovedPermanently, OtherMethods: MethodPreserve}, "POST", true, http.StatusPermanentRedirect, "https://example.com/did:plc:example"},
+		{"post preserve temporary", Redirects{HandleStatus: http.StatusFound, OtherMethods: MethodPreserve}, "POST", false, http.StatusTemporaryRedirect, "https://example.com/?from=alice.example.com"},
+		{"post reject", Redirects{OtherMethods: MethodReject}, "POST", true, http.StatusMethodNotAllowed, ""},
+		{"get is not rejected", Redirects{OtherMethods: MethodReject}, "GET", true, http.StatusTemporaryRedirect, "https://example.com/did:plc:example"},
+	}
+
+	for _, test := range tests {
+		res := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(res)
+
+		req, _ := http.NewRequest(test.method, "/", nil)
+		req.Host = "alice.example.com"
+		ctx.Request = req
+
+		ctx.Set("handle", Handle{Domain: "example.com", Username: "alice"})
+
+		if test.found {
+			ctx.Set("result", Result{HasDecentralizedID: true, DecentralizedID: "did:plc:example"})
+		} else {
+			ctx.Set("result", Result{HasDecentralizedID: false})
+		}
+
+		test.redirects.DIDTemplate = "https://example.com/{did}"
+		test.redirects.HandleTemplate = "https://example.com/?from={handle}"
+
+		RedirectUnmatchedRoute(test.redirects)(ctx)
+		ctx.Writer.WriteHeaderNow()
+
+		assert.Equal(t, test.expectedStatus, res.Code, test.name)
+		assert.Equal(t, test.expectedLocation, res.Header().Get("Location"), test.name)
+	}
+}
+
+func TestRejectedMethodListsAllowedMethods(t *testing.T) {
+	res := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(res)
+
+	req, _ := http.NewRequest("HEAD", "/", nil)
+	ctx.Request = req
+
+	ctx.Set("handle", Handle{Domain: "example.com", Username: "alice"})
+	ctx.Set("result", Result{})
+
+	RedirectUnmatchedRoute(Redirects{Head: MethodReject, OtherMethods: MethodReject})(ctx)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, res.Code)
+	assert.Equal(t, "GET", res.Header().Get("Allow"))
+}
+
+func TestHandleWithNestedUsernameIsAddedToRequestContext(t *testing.T) {
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Host = "alice.team.example.com"
+	ctx.Request = req
+
+	ParseHandleFromHostname(testProviderForRouter)(ctx)
+
+	assert.Equal(t, Handle{Domain: "example.com", Username: "alice.team"}, ctx.MustGet("handle"))
+}
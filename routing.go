@@ -0,0 +1,348 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var providerNamePattern = regexp.MustCompile(`^[a-z0-9]+$`)
+
+// DomainRoute sends the domains matching a pattern to a named provider. A
+// pattern is a domain (`example.com`), its subdomains (`*.example.com`), the
+// domain and its subdomains (`.example.com`), or every domain (`*`).
+type DomainRoute struct {
+	Pattern string
+	Name    string
+}
+
+// suffix is the end of the domains matched by a wildcard pattern.
+func (route DomainRoute) suffix() string {
+	switch {
+	case route.Pattern == "*":
+		return ""
+	case strings.HasPrefix(route.Pattern, "*."):
+		return route.Pattern[1:]
+	case strings.HasPrefix(route.Pattern, "."):
+		return route.Pattern
+	default:
+		return ""
+	}
+}
+
+func (route DomainRoute) isExact() bool {
+	return route.Pattern != "*" && !strings.HasPrefix(route.Pattern, "*.") && !strings.HasPrefix(route.Pattern, ".")
+}
+
+func (route DomainRoute) Matches(domain Domain) bool {
+	switch {
+	case route.Pattern == "*":
+		return true
+	case route.isExact():
+		return string(domain) == route.Pattern
+	case strings.HasPrefix(route.Pattern, ".") && string(domain) == route.Pattern[1:]:
+		return true
+	default:
+		return strings.HasSuffix(string(domain), route.suffix())
+	}
+}
+
+// ParseDomainRoutes validates a map of patterns to provider names, ordering
+// the routes so that the most specific pattern matching a domain is first:
+// domains, then wildcards by the length of their suffix, then `*`.
+func ParseDomainRoutes(patterns map[string]string) ([]DomainRoute, error) {
+	if len(patterns) == 0 {
+		return nil, errors.New("at least one domain route is required")
+	}
+
+	routes := make([]DomainRoute, 0, len(patterns))
+
+	for pattern, name := range patterns {
+		route := DomainRoute{Pattern: strings.ToLower(strings.TrimSpace(pattern)), Name: strings.TrimSpace(name)}
+
+		if !providerNamePattern.MatchString(route.Name) {
+			return nil, fmt.Errorf("route %s must name a provider of lowercase letters and numbers, not %q", pattern, name)
+		}
+
+		if route.Pattern != "*" {
+			domain := strings.TrimPrefix(strings.TrimPrefix(route.Pattern, "*"), ".")
+
+			if err := ValidateHostname(domain); err != nil {
+				return nil, fmt.Errorf("route %s is not a domain, `*.domain`, `.domain` or `*`: %w", pattern, err)
+			}
+		}
+
+		routes = append(routes, route)
+	}
+
+	slices.SortFunc(routes, func(a, b DomainRoute) int {
+		if a.isExact() != b.isExact() {
+			if a.isExact() {
+				return -1
+			}
+
+			return 1
+		}
+
+		if len(a.suffix()) != len(b.suffix()) {
+			return len(b.suffix()) - len(a.suffix())
+		}
+
+		// `*.example.com` does not match example.com, so it is more specific.
+		return strings.Compare(a.Pattern, b.Pattern)
+	})
+
+	return routes, nil
+}
+
+// RoutingProvider sends each domain to the provider of the first route
+// matching it, providing for no other domains.
+type RoutingProvider struct {
+	routes    []DomainRoute
+	names     []string
+	providers map[string]ProvidesDecentralizedIDs
+}
+
+func NewRoutingProvider(routes []DomainRoute, providers map[string]ProvidesDecentralizedIDs) *RoutingProvider {
+	names := make([]string, 0, len(providers))
+
+	for name := range providers {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return &RoutingProvider{routes, names, providers}
+}
+
+// route finds the provider for a domain, or nil when no route matches it.
+func (router *RoutingProvider) route(domain Domain) ProvidesDecentralizedIDs {
+	for _, route := range router.routes {
+		if route.Matches(domain) {
+			return router.providers[route.Name]
+		}
+	}
+
+	return nil
+}
+
+func (router *RoutingProvider) GetDecentralizedIDForHandle(ctx context.Context, handle Handle) (DecentralizedID, error) {
+	provider := router.route(handle.Domain)
+
+	if provider == nil {
+		return "", &CannotGetHandelsFromDomainError{domain: handle.Domain}
+	}
+
+	return provider.GetDecentralizedIDForHandle(ctx, handle)
+}
+
+func (router *RoutingProvider) CanProvideForDomain(ctx context.Context, domain Domain) (bool, error) {
+	provider := router.route(domain)
+
+	if provider == nil {
+		return false, nil
+	}
+
+	return provider.CanProvideForDomain(ctx, domain)
+}
+
+// IsHealthy is only healthy when every provider is healthy.
+func (router *RoutingProvider) IsHealthy(ctx context.Context) (bool, string) {
+	healthy := true
+	statuses := make([]string, len(router.names))
+
+	for i, name := range router.names {
+		providerHealthy, status := router.providers[name].IsHealthy(ctx)
+
+		healthy = healthy && providerHealthy
+		statuses[i] = fmt.Sprintf("%s: %s", name, status)
+	}
+
+	return healthy, strings.Join(statuses, "; ")
+}
+
+// routeAs finds the provider for a domain with a capability, failing when the
+// domain is not routed or its provider does not have the capability.
+func routeAs[T any](router *RoutingProvider, domain Domain) (T, error) {
+	var none T
+
+	provider := router.route(domain)
+
+	if provider == nil {
+		return none, &CannotGetHandelsFromDomainError{domain: domain}
+	}
+
+	capable, ok := ProviderAs[T](provider)
+
+	if !ok {
+		return none, fmt.Errorf("the provider for %s cannot be changed: %w", domain, ErrProviderIsReadOnly)
+	}
+
+	return capable, nil
+}
+
+func (router *RoutingProvider) ListDecentralizedIDs(ctx context.Context) (MapOfDids, error) {
+	dids := make(MapOfDids)
+
+	for _, name := range router.names {
+		manager, ok := ProviderAs[ManagesDecentralizedIDs](router.providers[name])
+
+		if !ok {
+			continue
+		}
+
+		providerDids, err := manager.ListDecentralizedIDs(ctx)
+
+		if err != nil {
+			return nil, fmt.Errorf("%s provider: %w", name, err)
+		}
+
+		for hostname, did := range providerDids {
+			dids[hostname] = did
+		}
+	}
+
+	return dids, nil
+}
+
+func (router *RoutingProvider) SetDecentralizedIDForHandle(ctx context.Context, handle Handle, did DecentralizedID) error {
+	manager, err := routeAs[ManagesDecentralizedIDs](router, handle.Domain)
+
+	if err != nil {
+		return err
+	}
+
+	return manager.SetDecentralizedIDForHandle(ctx, handle, did)
+}
+
+func (router *RoutingProvider) DeleteDecentralizedIDForHandle(ctx context.Context, handle Handle) error {
+	manager, err := routeAs[ManagesDecentralizedIDs](router, handle.Domain)
+
+	if err != nil {
+		return err
+	}
+
+	return manager.DeleteDecentralizedIDForHandle(ctx, handle)
+}
+
+func (router *RoutingProvider) ListDomains(ctx context.Context) ([]Domain, error) {
+	var domains []Domain
+
+	for _, name := range router.names {
+		manager, ok := ProviderAs[ManagesDecentralizedIDs](router.providers[name])
+
+		if !ok {
+			continue
+		}
+
+		providerDomains, err := manager.ListDomains(ctx)
+
+		if err != nil {
+			return nil, fmt.Errorf("%s provider: %w", name, err)
+		}
+
+		domains = append(domains, providerDomains...)
+	}
+
+	slices.Sort(domains)
+
+	return slices.Compact(domains), nil
+}
+
+func (router *RoutingProvider) AddDomain(ctx context.Context, domain Domain) error {
+	manager, err := routeAs[ManagesDecentralizedIDs](router, domain)
+
+	if err != nil {
+		return err
+	}
+
+	return manager.AddDomain(ctx, domain)
+}
+
+func (router *RoutingProvider) RemoveDomain(ctx context.Context, domain Domain) error {
+	manager, err := routeAs[ManagesDecentralizedIDs](router, domain)
+
+	if err != nil {
+		return err
+	}
+
+	return manager.RemoveDomain(ctx, domain)
+}
+
+func (router *RoutingProvider) GetDomainSettings(ctx context.Context, domain Domain) (DomainSettings, error) {
+	settings, err := routeAs[ProvidesDomainSettings](router, domain)
+
+	if err != nil {
+		return DomainSettings{}, nil
+	}
+
+	return settings.GetDomainSettings(ctx, domain)
+}
+
+func (router *RoutingProvider) SetDomainSettings(ctx context.Context, domain Domain, settings DomainSettings) error {
+	manager, err := routeAs[ManagesDomainSettings](router, domain)
+
+	if err != nil {
+		return err
+	}
+
+	return manager.SetDomainSettings(ctx, domain, settings)
+}
+
+func (router *RoutingProvider) GetDIDDocument(ctx context.Context, handle Handle) (*DIDDocument, error) {
+	documents, err := routeAs[ProvidesDIDDocuments](router, handle.Domain)
+
+	if err != nil {
+		return nil, nil
+	}
+
+	return documents.GetDIDDocument(ctx, handle)
+}
+
+func (router *RoutingProvider) SetDIDDocument(ctx context.Context, handle Handle, document DIDDocument) error {
+	manager, err := routeAs[ManagesDIDDocuments](router, handle.Domain)
+
+	if err != nil {
+		return err
+	}
+
+	return manager.SetDIDDocument(ctx, handle, document)
+}
+
+func (router *RoutingProvider) DeleteDIDDocument(ctx context.Context, handle Handle) error {
+	manager, err := routeAs[ManagesDIDDocuments](router, handle.Domain)
+
+	if err != nil {
+		return err
+	}
+
+	return manager.DeleteDIDDocument(ctx, handle)
+}
+
+func (router *RoutingProvider) Unwrap() []ProvidesDecentralizedIDs {
+	providers := make([]ProvidesDecentralizedIDs, len(router.names))
+
+	for i, name := range router.names {
+		providers[i] = router.providers[name]
+	}
+
+	return providers
+}
+
+func (router *RoutingProvider) Close() error {
+	var errs []error
+
+	for _, name := range router.names {
+		if closer, ok := router.providers[name].(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("%s provider: %w", name, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDomainRoutesAreOrderedBySpecificity(t *testing.T) {
+	routes, err := ParseDomainRoutes(map[string]string{
+		"*":                      "fallback",
+		".example.com":           "ours",
+		"*.example.com":          "subdomains",
+		"*.customer.example.com": "customer",
+		"Customer.Example.com":   "customer",
+		"handles.example.net":    "ours",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []DomainRoute{
+		{Pattern: "customer.example.com", Name: "customer"},
+		{Pattern: "handles.example.net", Name: "ours"},
+		{Pattern: "*.customer.example.com", Name: "customer"},
+		{Pattern: "*.example.com", Name: "subdomains"},
+		{Pattern: ".example.com", Name: "ours"},
+		{Pattern: "*", Name: "fallback"},
+	}, routes)
+}
+
+func TestInvalidDomainRoutesAreRejected(t *testing.T) {
+	tests := []map[string]string{
+		{},
+		{"example.com": ""},
+		{"example.com": "Customer_1"},
+		{"example.local": "ours"},
+		{"**.example.com": "ours"},
+		{"example.*": "ours"},
+	}
+
+	for _, patterns := range tests {
+		_, err := ParseDomainRoutes(patterns)
+
+		assert.Error(t, err, "%v", patterns)
+	}
+}
+
+func TestDomainRoutesMatchDomains(t *testing.T) {
+	tests := []struct {
+		pattern string
+		domain  Domain
+		matches bool
+	}{
+		{"example.com", "example.com", true},
+		{"example.com", "www.example.com", false},
+		{"*.example.com", "www.example.com", true},
+		{"*.example.com", "a.b.example.com", true},
+		{"*.example.com", "example.com", false},
+		{"*.example.com", "badexample.com", false},
+		{".example.com", "example.com", true},
+		{".example.com", "www.example.com", true},
+		{".example.com", "badexample.com", false},
+		{"*", "example.net", true},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.matches, DomainRoute{Pattern: test.pattern}.Matches(test.domain), "%s %s", test.pattern, test.domain)
+	}
+}
+
+func newTestRoutingProvider(t *testing.T) (*RoutingProvider, *InMemoryProvider, *InMemoryProvider) {
+	routes, err := ParseDomainRoutes(map[string]string{
+		"example.com":           "ours",
+		".customer.example.net": "customer",
+	})
+
+	assert.NoError(t, err)
+
+	ours := NewInMemoryProvider(MapOfDids{"alice.example.com": "did:plc:example001"}, MapOfDomains{"example.com": true})
+	customer := NewInMemoryProvider(MapOfDids{"bob.customer.example.net": "did:plc:example002"}, MapOfDomains{"customer.example.net": true})
+
+	return NewRoutingProvider(routes, map[string]ProvidesDecentralizedIDs{"ours": ours, "customer": customer}), ours, customer
+}
+
+func TestRoutingProviderResolvesHandlesWithTheProviderOfTheirDomain(t *testing.T) {
+	router, _, _ := newTestRoutingProvider(t)
+	ctx := context.Background()
+
+	did, err := router.GetDecentralizedIDForHandle(ctx, Handle{Username: "alice", Domain: "example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, DecentralizedID("did:plc:example001"), did)
+
+	did, err = router.GetDecentralizedIDForHandle(ctx, Handle{Username: "bob", Domain: "customer.example.net"})
+	assert.NoError(t, err)
+	assert.Equal(t, DecentralizedID("did:plc:example002"), did)
+
+	_, err = router.GetDecentralizedIDForHandle(ctx, Handle{Username: "carol", Domain: "example.org"})
+	assert.ErrorIs(t, err, (*CannotGetHandelsFromDomainError)(nil))
+
+	// Routed to a provider which does not provide for the domain.
+	_, err = router.GetDecentralizedIDForHandle(ctx, Handle{Username: "dave", Domain: "eu.customer.example.net"})
+	assert.ErrorIs(t, err, (*CannotGetHandelsFromDomainError)(nil))
+
+	canProvide, err := router.CanProvideForDomain(ctx, "example.org")
+	assert.NoError(t, err)
+	assert.False(t, canProvide)
+}
+
+func TestRoutingProviderChangesTheProviderOfTheDomain(t *testing.T) {
+	router, ours, customer := newTestRoutingProvider(t)
+	ctx := context.Background()
+
+	assert.NoError(t, router.AddDomain(ctx, "eu.customer.example.net"))
+	assert.NoError(t, router.SetDecentralizedIDForHandle(ctx, Handle{Username: "dave", Domain: "eu.customer.example.net"}, "did:plc:example004"))
+
+	dids, _ := customer.ListDecentralizedIDs(ctx)
+	assert.Equal(t, DecentralizedID("did:plc:example004"), dids["dave.eu.customer.example.net"])
+
+	dids, _ = ours.ListDecentralizedIDs(ctx)
+	assert.NotContains(t, dids, Hostname("dave.eu.customer.example.net"))
+
+	domains, err := router.ListDomains(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []Domain{"customer.example.net", "eu.customer.example.net", "example.com"}, domains)
+
+	assert.ErrorIs(t, router.AddDomain(ctx, "example.org"), (*CannotGetHandelsFromDomainError)(nil))
+}
+
+func TestRoutingProviderCannotChangeProvidersWhichAreReadOnly(t *testing.T) {
+	routes, _ := ParseDomainRoutes(map[string]string{"*": "sheets"})
+	router := NewRoutingProvider(routes, map[string]ProvidesDecentralizedIDs{"sheets": &SheetsProvider{}})
+
+	err := router.SetDecentralizedIDForHandle(context.Background(), Handle{Username: "alice", Domain: "example.com"}, "did:plc:example001")
+
+	assert.ErrorIs(t, err, ErrProviderIsReadOnly)
+}
+
+func TestRoutingProviderIsOnlyHealthyWhenEveryProviderIsHealthy(t *testing.T) {
+	router, _, customer := newTestRoutingProvider(t)
+
+	healthy, status := router.IsHealthy(context.Background())
+	assert.True(t, healthy)
+	assert.Equal(t, "customer: Available with 1 handles for 1 domains; ours: Available with 1 handles for 1 domains", status)
+
+	customer.SetHealthy(false)
+
+	healthy, _ = router.IsHealthy(context.Background())
+	assert.False(t, healthy)
+}
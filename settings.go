@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DomainSettings override the global settings for handles on a domain; empty
+// settings fall back to the global values.
+type DomainSettings struct {
+	RedirectDIDTemplate    URLTemplate `json:"redirectDidTemplate,omitempty" yaml:"redirectDidTemplate"`
+	RedirectHandleTemplate URLTemplate `json:"redirectHandleTemplate,omitempty" yaml:"redirectHandleTemplate"`
+	RedirectStatus         int         `json:"redirectStatus,omitempty" yaml:"redirectStatus"`
+
+	// Metadata is available to URL templates as `{domain.<key>}`.
+	Metadata map[string]string `json:"metadata,omitempty" yaml:"metadata"`
+}
+
+var redirectStatuses = []int{
+	http.StatusMovedPermanently,
+	http.StatusFound,
+	http.StatusSeeOther,
+	http.StatusTemporaryRedirect,
+	http.StatusPermanentRedirect,
+}
+
+func ValidateRedirectStatus(status int) error {
+	if !slices.Contains(redirectStatuses, status) {
+		return fmt.Errorf("redirect status %d is not one of 301, 302, 303, 307 or 308", status)
+	}
+
+	return nil
+}
+
+func (settings DomainSettings) Validate() error {
+	if err := settings.RedirectDIDTemplate.Validate(); err != nil {
+		return err
+	}
+
+	if err := settings.RedirectHandleTemplate.Validate(); err != nil {
+		return err
+	}
+
+	if settings.RedirectStatus != 0 {
+		return ValidateRedirectStatus(settings.RedirectStatus)
+	}
+
+	return nil
+}
+
+func (settings DomainSettings) IsEmpty() bool {
+	return settings.RedirectDIDTemplate == "" &&
+		settings.RedirectHandleTemplate == "" &&
+		settings.RedirectStatus == 0 &&
+		len(settings.Metadata) == 0
+}
+
+// Or fills the empty settings from fallback.
+func (settings DomainSettings) Or(fallback DomainSettings) DomainSettings {
+	if settings.RedirectDIDTemplate == "" {
+		settings.RedirectDIDTemplate = fallback.RedirectDIDTemplate
+	}
+
+	if settings.RedirectHandleTemplate == "" {
+		settings.RedirectHandleTemplate = fallback.RedirectHandleTemplate
+	}
+
+	if settings.RedirectStatus == 0 {
+		settings.RedirectStatus = fallback.RedirectStatus
+	}
+
+	if settings.Metadata == nil {
+		settings.Metadata = fallback.Metadata
+	}
+
+	return settings
+}
+
+// MapOfDomainSettings is read from JSON (`MEMORY_DOMAIN_SETTINGS`).
+type MapOfDomainSettings map[Domain]DomainSettings
+
+func (settings *MapOfDomainSettings) UnmarshalText(text []byte) error {
+	return json.Unmarshal(text, (*map[Domain]DomainSettings)(settings))
+}
+
+// ProvidesDomainSettings is implemented by providers which store settings for
+// each domain.
+type ProvidesDomainSettings interface {
+	GetDomainSettings(ctx context.Context, domain Domain) (DomainSettings, error)
+}
+
+// ManagesDomainSettings is implemented by providers whose settings can be
+// changed through the admin API.
+type ManagesDomainSettings interface {
+	SetDomainSettings(ctx context.Context, domain Domain, settings DomainSettings) error
+}
+
+func WithDomainSettings(provider ProvidesDomainSettings) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		handle := c.MustGet("handle").(Handle)
+
+		settings, err := provider.GetDomainSettings(c, handle.Domain)
+
+		if err != nil {
+			_ = c.AbortWithError(http.StatusBadGateway, err)
+			return
+		}
+
+		c.Set("settings", settings)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDomainSettingsFallBackToGlobalSettings(t *testing.T) {
+	global := DomainSettings{
+		RedirectDIDTemplate:    "https://example.com/{did}",
+		RedirectHandleTemplate: "https://example.com/{handle}",
+		RedirectStatus:         http.StatusTemporaryRedirect,
+	}
+
+	settings := DomainSettings{RedirectDIDTemplate: "https://example.net/{did}"}.Or(global)
+
+	assert.Equal(t, DomainSettings{
+		RedirectDIDTemplate:    "https://example.net/{did}",
+		RedirectHandleTemplate: "https://example.com/{handle}",
+		RedirectStatus:         http.StatusTemporaryRedirect,
+	}, settings)
+}
+
+func TestDomainSettingsRejectInvalidRedirectStatus(t *testing.T) {
+	assert.NoError(t, DomainSettings{}.Validate())
+	assert.NoError(t, DomainSettings{RedirectStatus: http.StatusMovedPermanently}.Validate())
+	assert.Error(t, DomainSettings{RedirectStatus: http.StatusOK}.Validate())
+}
+
+func TestUnmatchedRouteRedirectsUsingDomainSettings(t *testing.T) {
+	provider := NewInMemoryProvider(MapOfDids{
+		"alice.example.com": "did:plc:example001",
+		"alice.example.net": "did:plc:example002",
+	}, MapOfDomains{"example.com": true, "example.net": true})
+
+	assert.NoError(t, provider.SetDomainSettings(context.Background(), "example.net", DomainSettings{
+		RedirectDIDTemplate:    "https://example.net/profile/{did}",
+		RedirectHandleTemplate: "https://{domain.community}.example.net/join?handle={handle|urlquery}",
+		RedirectStatus:         http.StatusMovedPermanently,
+		Metadata:               map[string]string{"community": "gardeners"},
+	}))
+
+	router := gin.New()
+
+	AddApplicationRoutes(router, Config{
+		Provider:               provider,
+		Logger:                 testFileLogger,
+		RedirectDIDTemplate:    "https://example.com/{did}",
+		RedirectHandleTemplate: "https://example.com/{handle}",
+	})
+
+	tests := []struct {
+		host             string
+		expectedStatus   int
+		expectedLocation string
+	}{
+		{"alice.example.com", http.StatusTemporaryRedirect, "https://example.com/did:plc:example001"},
+		{"alice.example.net", http.StatusMovedPermanently, "https://example.net/profile/did:plc:example002"},
+		{"bob.example.net", http.StatusMovedPermanently, "https://gardeners.example.net/join?handle=bob.example.net"},
+	}
+
+	for _, test := range tests {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Host = test.host
+		router.ServeHTTP(res, req)
+
+		assert.Equal(t, test.expectedStatus, res.Code, test.host)
+		assert.Equal(t, test.expectedLocation, res.Header().Get("Location"), test.host)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type SheetsProvider struct {
+	didsURL    string
+	domainsURL string
+	client     *http.Client
+	logger     *slog.Logger
+	current    atomic.Pointer[InMemoryProvider]
+	stop       context.CancelFunc
+
+	mutex       sync.Mutex
+	refreshedAt time.Time
+	lastError   error
+}
+
+func NewSheetsProvider(didsURL string, domainsURL string, interval time.Duration, client *http.Client, logger *slog.Logger) (*SheetsProvider, error) {
+	ctx, stop := context.WithCancel(context.Background())
+
+	sheets := &SheetsProvider{
+		didsURL:    didsURL,
+		domainsURL: domainsURL,
+		client:     client,
+		logger:     logger,
+		stop:       stop,
+	}
+
+	if err := sheets.refresh(ctx); err != nil {
+		stop()
+		return nil, err
+	}
+
+	go sheets.refreshEvery(ctx, interval)
+
+	return sheets, nil
+}
+
+func (sheets *SheetsProvider) GetDecentralizedIDForHandle(ctx context.Context, handle Handle) (DecentralizedID, error) {
+	return sheets.current.Load().GetDecentralizedIDForHandle(ctx, handle)
+}
+
+func (sheets *SheetsProvider) CanProvideForDomain(ctx context.Context, domain Domain) (bool, error) {
+	return sheets.current.Load().CanProvideForDomain(ctx, domain)
+}
+
+func (sheets *SheetsProvider) IsHealthy(ctx context.Context) (bool, string) {
+	healthy, status := sheets.current.Load().IsHealthy(ctx)
+
+	sheets.mutex.Lock()
+	defer sheets.mutex.Unlock()
+
+	status = fmt.Sprintf("%s, refreshed %s ago", status, time.Since(sheets.refreshedAt).Round(time.Second))
+
+	if sheets.lastError != nil {
+		status = fmt.Sprintf("%s (last refresh failed: %s)", status, sheets.lastError)
+	}
+
+	return healthy, status
+}
+
+func (sheets *SheetsProvider) Close() error {
+	sheets.stop()
+	return nil
+}
+
+func (sheets *SheetsProvider) refreshEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := sheets.refresh(ctx); err != nil {
+				sheets.logger.Error("could not refresh sheets, continuing with previous copy", "error", err)
+			}
+		}
+	}
+}
+
+func (sheets *SheetsProvider) refresh(ctx context.Context) error {
+	dids := make(MapOfDids)
+	domains := make(MapOfDomains)
+
+	err := sheets.fetch(ctx, sheets.didsURL, "handle", 2, func(row []string) {
+		dids[Hostname(strings.ToLower(row[0]))] = DecentralizedID(row[1])
+	})
+
+	if err == nil {
+		err = sheets.fetch(ctx, sheets.domainsURL, "domain", 1, func(row []string) {
+			domains[Domain(strings.ToLower(row[0]))] = true
+		})
+	}
+
+	sheets.mutex.Lock()
+	defer sheets.mutex.Unlock()
+
+	sheets.lastError = err
+
+	if err != nil {
+		return err
+	}
+
+	sheets.current.Store(NewInMemoryProvider(dids, domains))
+	sheets.refreshedAt = time.Now()
+
+	return nil
+}
+
+// fetch downloads a CSV sheet and calls add for every row which has at least
+// the given number of columns. Blank rows and a header row are skipped.
+func (sheets *SheetsProvider) fetch(ctx context.Context, url string, header string, columns int, add func(row []string)) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+
+	if err != nil {
+		return err
+	}
+
+	response, err := sheets.client.Do(request)
+
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("sheet %s responded with %s", url, response.Status)
+	}
+
+	rows := csv.NewReader(response.Body)
+	rows.FieldsPerRecord = -1
+	rows.TrimLeadingSpace = true
+
+	for line := 1; ; line++ {
+		row, err := rows.Read()
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("sheet %s could not be read: %w", url, err)
+		}
+
+		if strings.TrimSpace(strings.Join(row, "")) == "" || (line == 1 && strings.EqualFold(row[0], header)) {
+			continue
+		}
+
+		if len(row) < columns || row[columns-1] == "" {
+			return fmt.Errorf("sheet %s line %d has fewer than %d columns", url, line, columns)
+		}
+
+		add(row)
+	}
+}
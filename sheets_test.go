@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testSheetsServer struct {
+	dids    atomic.Value
+	domains atomic.Value
+	failing atomic.Bool
+}
+
+func newTestSheetsServer(t *testing.T, dids string, domains string) (*testSheetsServer, *httptest.Server) {
+	sheets := &testSheetsServer{}
+	sheets.dids.Store(dids)
+	sheets.domains.Store(domains)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if sheets.failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/dids.csv":
+			_, _ = w.Write([]byte(sheets.dids.Load().(string)))
+		case "/domains.csv":
+			_, _ = w.Write([]byte(sheets.domains.Load().(string)))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	t.Cleanup(server.Close)
+
+	return sheets, server
+}
+
+func newTestSheetsProvider(t *testing.T, server *httptest.Server) *SheetsProvider {
+	provider, err := NewSheetsProvider(
+		server.URL+"/dids.csv",
+		server.URL+"/domains.csv",
+		time.Hour,
+		server.Client(),
+		testFileLogger,
+	)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { _ = provider.Close() })
+
+	return provider
+}
+
+func TestSheetsProviderHasDecentralizedIdForHandle(t *testing.T) {
+	_, server := newTestSheetsServer(t, "handle,did,notes\nAlice.example.com,did:plc:example001,\n,,\n", "domain\nexample.com\n")
+	provider := newTestSheetsProvider(t, server)
+
+	did, err := provider.GetDecentralizedIDForHandle(context.Background(), Handle{Domain: "example.com", Username: "alice"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, DecentralizedID("did:plc:example001"), did)
+}
+
+func TestSheetsProviderCannotStartWithoutSheets(t *testing.T) {
+	sheets, server := newTestSheetsServer(t, "", "")
+	sheets.failing.Store(true)
+
+	_, err := NewSheetsProvider(server.URL+"/dids.csv", server.URL+"/domains.csv", time.Hour, server.Client(), testFileLogger)
+
+	assert.NotNil(t, err)
+}
+
+func TestSheetsProviderRefreshesSheets(t *testing.T) {
+	sheets, server := newTestSheetsServer(t, "alice.example.com,did:plc:example001\n", "example.com\n")
+	provider := newTestSheetsProvider(t, server)
+
+	sheets.dids.Store("alice.example.com,did:plc:example002\n")
+
+	assert.Nil(t, provider.refresh(context.Background()))
+
+	did, _ := provider.GetDecentralizedIDForHandle(context.Background(), Handle{Domain: "example.com", Username: "alice"})
+	assert.Equal(t, DecentralizedID("did:plc:example002"), did)
+}
+
+func TestSheetsProviderKeepsLastGoodCopyWhenRefreshFails(t *testing.T) {
+	sheets, server := newTestSheetsServer(t, "alice.example.com,did:plc:example001\n", "example.com\n")
+	provider := newTestSheetsProvider(t, server)
+
+	sheets.failing.Store(true)
+
+	assert.NotNil(t, provider.refresh(context.Background()))
+
+	did, _ := provider.GetDecentralizedIDForHandle(context.Background(), Handle{Domain: "example.com", Username: "alice"})
+	assert.Equal(t, DecentralizedID("did:plc:example001"), did)
+
+	healthy, status := provider.IsHealthy(context.Background())
+	assert.True(t, healthy)
+	assert.Contains(t, status, "refreshed")
+	assert.Contains(t, status, "last refresh failed")
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Lifecycle tracks whether the server is draining so that health checks fail
+// before the listener stops accepting connections.
+type Lifecycle struct {
+	draining atomic.Bool
+}
+
+func (lifecycle *Lifecycle) Drain() {
+	lifecycle.draining.Store(true)
+}
+
+func (lifecycle *Lifecycle) IsDraining() bool {
+	return lifecycle != nil && lifecycle.draining.Load()
+}
+
+// Shutdown fails health checks for the delay, waits up to the timeout for
+// in-flight requests and DNS queries to finish and then closes the provider.
+func Shutdown(config Config, servers []*http.Server, dnsServers []*dns.Server) error {
+	config.Lifecycle.Drain()
+
+	config.Logger.Info("draining before shutting down", "delay", config.ShutdownDelay, "timeout", config.ShutdownTimeout)
+
+	time.Sleep(config.ShutdownDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+
+	var errs []error
+
+	for _, server := range servers {
+		if err := server.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("could not drain HTTP server (%s): %w", server.Addr, err))
+		}
+	}
+
+	for _, dnsServer := range dnsServers {
+		if err := dnsServer.ShutdownContext(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("could not shut down DNS server (%s): %w", dnsServer.Net, err))
+		}
+	}
+
+	if closer, ok := config.Provider.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("could not close provider: %w", err))
+		}
+	}
+
+	if config.TracerProvider != nil {
+		if err := config.TracerProvider.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("could not flush spans: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
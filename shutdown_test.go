@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type closeableProvider struct {
+	ProvidesDecentralizedIDs
+	closed atomic.Bool
+}
+
+func (provider *closeableProvider) Close() error {
+	provider.closed.Store(true)
+	return nil
+}
+
+func TestShutdownDrainsRequestsBeforeClosingProvider(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	started := make(chan struct{})
+	provider := &closeableProvider{ProvidesDecentralizedIDs: NewInMemoryProvider(MapOfDids{}, MapOfDomains{})}
+	config := Config{
+		Provider:        NewCachingProvider(provider, 10, time.Minute, time.Minute, time.Minute),
+		Logger:          testFileLogger,
+		Lifecycle:       &Lifecycle{},
+		ShutdownTimeout: 5 * time.Second,
+	}
+
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		assert.False(t, provider.closed.Load(), "provider was closed before request finished")
+		_, _ = w.Write([]byte("finished"))
+	})}
+
+	go func() { _ = server.Serve(listener) }()
+
+	responses := make(chan string, 1)
+
+	go func() {
+		res, err := http.Get("http://" + listener.Addr().String())
+
+		if err != nil {
+			responses <- err.Error()
+			return
+		}
+
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		responses <- string(body)
+	}()
+
+	<-started
+
+	assert.NoError(t, Shutdown(config, []*http.Server{server}, nil))
+	assert.Equal(t, "finished", <-responses)
+	assert.True(t, provider.closed.Load())
+	assert.True(t, config.Lifecycle.IsDraining())
+}
+
+func TestShutdownGivesUpAfterTimeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})}
+
+	go func() { _ = server.Serve(listener) }()
+	go func() { _, _ = http.Get("http://" + listener.Addr().String()) }()
+
+	<-started
+
+	err = Shutdown(Config{
+		Provider:        NewInMemoryProvider(MapOfDids{}, MapOfDomains{}),
+		Logger:          testFileLogger,
+		Lifecycle:       &Lifecycle{},
+		ShutdownTimeout: 50 * time.Millisecond,
+	}, []*http.Server{server}, nil)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
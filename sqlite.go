@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+type SQLiteHandles struct {
+	db           *sql.DB
+	didsTable    string
+	domainsTable string
+}
+
+func NewSQLiteHandlesProvider(path string, didsTable string, domainsTable string) (*SQLiteHandles, error) {
+	db, err := sql.Open("sqlite", path)
+
+	if err != nil {
+		return &SQLiteHandles{}, err
+	}
+
+	// SQLite allows a single writer and each connection to `:memory:` is its
+	// own database, so all queries share one connection.
+	db.SetMaxOpenConns(1)
+
+	sqlite := &SQLiteHandles{db, didsTable, domainsTable}
+
+	healthy, status := sqlite.IsHealthy(context.Background())
+
+	if !healthy {
+		db.Close()
+		return &SQLiteHandles{}, errors.New(status)
+	}
+
+	if err := sqlite.createTables(context.Background()); err != nil {
+		db.Close()
+		return &SQLiteHandles{}, err
+	}
+
+	return sqlite, nil
+}
+
+func (sqlite *SQLiteHandles) GetDecentralizedIDForHandle(ctx context.Context, handle Handle) (DecentralizedID, error) {
+	canProvide, err := sqlite.CanProvideForDomain(ctx, handle.Domain)
+
+	if err != nil {
+		return "", err
+	}
+
+	if !canProvide {
+		return "", &CannotGetHandelsFromDomainError{domain: handle.Domain}
+	}
+
+	var did DecentralizedID
+
+	query := fmt.Sprintf(
+		"select did from %s where LOWER(handle) = LOWER(?)",
+		sqliteIdentifier(sqlite.didsTable),
+	)
+
+	err = sqlite.db.QueryRowContext(ctx, query, handle.String()).Scan(&did)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return did, nil
+}
+
+func (sqlite *SQLiteHandles) CanProvideForDomain(ctx context.Context, domain Domain) (bool, error) {
+	exists := false
+	patterns := domainPatternNames(domain)
+	arguments := make([]any, len(patterns))
+
+	for i, pattern := range patterns {
+		arguments[i] = pattern
+	}
+
+	query := fmt.Sprintf(
+		"select exists(select 1 from %s where domain in (%s))",
+		sqliteIdentifier(sqlite.domainsTable),
+		strings.TrimSuffix(strings.Repeat("?, ", len(patterns)), ", "),
+	)
+
+	err := sqlite.db.QueryRowContext(ctx, query, arguments...).Scan(&exists)
+
+	return exists, err
+}
+
+func (sqlite *SQLiteHandles) IsHealthy(ctx context.Context) (bool, string) {
+	if err := sqlite.db.PingContext(ctx); err != nil {
+		return false, err.Error()
+	}
+
+	return true, "Connected to database"
+}
+
+func (sqlite *SQLiteHandles) Close() error {
+	return sqlite.db.Close()
+}
+
+func (sqlite *SQLiteHandles) ListDecentralizedIDs(ctx context.Context) (MapOfDids, error) {
+	query := fmt.Sprintf(
+		"select LOWER(handle), did from %s",
+		sqliteIdentifier(sqlite.didsTable),
+	)
+
+	rows, err := sqlite.db.QueryContext(ctx, query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	dids := make(MapOfDids)
+
+	for rows.Next() {
+		var hostname Hostname
+		var did DecentralizedID
+
+		if err := rows.Scan(&hostname, &did); err != nil {
+			return nil, err
+		}
+
+		dids[hostname] = did
+	}
+
+	return dids, rows.Err()
+}
+
+func (sqlite *SQLiteHandles) SetDecentralizedIDForHandle(ctx context.Context, handle Handle, did DecentralizedID) error {
+	query := fmt.Sprintf(
+		"insert into %s (handle, did) values (?, ?) on conflict (handle) do update set did = excluded.did",
+		sqliteIdentifier(sqlite.didsTable),
+	)
+
+	_, err := sqlite.db.ExecContext(ctx, query, handle.String(), did)
+
+	return err
+}
+
+func (sqlite *SQLiteHandles) DeleteDecentralizedIDForHandle(ctx context.Context, handle Handle) error {
+	query := fmt.Sprintf(
+		"delete from %s where LOWER(handle) = LOWER(?)",
+		sqliteIdentifier(sqlite.didsTable),
+	)
+
+	_, err := sqlite.db.ExecContext(ctx, query, handle.String())
+
+	return err
+}
+
+func (sqlite *SQLiteHandles) ListDomains(ctx context.Context) ([]Domain, error) {
+	query := fmt.Sprintf(
+		"select domain from %s order by domain",
+		sqliteIdentifier(sqlite.domainsTable),
+	)
+
+	rows, err := sqlite.db.QueryContext(ctx, query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	domains := []Domain{}
+
+	for rows.Next() {
+		var domain Domain
+
+		if err := rows.Scan(&domain); err != nil {
+			return nil, err
+		}
+
+		domains = append(domains, domain)
+	}
+
+	return domains, rows.Err()
+}
+
+func (sqlite *SQLiteHandles) AddDomain(ctx context.Context, domain Domain) error {
+	query := fmt.Sprintf(
+		"insert into %s (domain) values (?) on conflict (domain) do nothing",
+		sqliteIdentifier(sqlite.domainsTable),
+	)
+
+	_, err := sqlite.db.ExecContext(ctx, query, domain)
+
+	return err
+}
+
+func (sqlite *SQLiteHandles) RemoveDomain(ctx context.Context, domain Domain) error {
+	query := fmt.Sprintf(
+		"delete from %s where domain = ?",
+		sqliteIdentifier(sqlite.domainsTable),
+	)
+
+	_, err := sqlite.db.ExecContext(ctx, query, domain)
+
+	return err
+}
+
+func (sqlite *SQLiteHandles) createTables(ctx context.Context) error {
+	_, err := sqlite.db.ExecContext(ctx, fmt.Sprintf(
+		`create table if not exists %s (handle text primary key collate nocase, did text not null);
+		create table if not exists %s (domain text primary key);`,
+		sqliteIdentifier(sqlite.didsTable),
+		sqliteIdentifier(sqlite.domainsTable),
+	))
+
+	return err
+}
+
+func sqliteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSQLiteProvider(t *testing.T) *SQLiteHandles {
+	provider, err := NewSQLiteHandlesProvider(filepath.Join(t.TempDir(), "handles.db"), "dids", "domains")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { _ = provider.Close() })
+
+	_, err = provider.db.Exec(`
+		insert into dids (handle, did) values ('Alice.example.com', 'did:plc:example001');
+		insert into domains (domain) values ('example.com');
+	`)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return provider
+}
+
+func TestSQLiteProviderHasDecentralizedIdForHandle(t *testing.T) {
+	provider := newTestSQLiteProvider(t)
+
+	did, err := provider.GetDecentralizedIDForHandle(context.Background(), Handle{Domain: "example.com", Username: "ALICE"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, DecentralizedID("did:plc:example001"), did)
+}
+
+func TestSQLiteProviderHasNoDecentralizedIdForUnknownHandle(t *testing.T) {
+	provider := newTestSQLiteProvider(t)
+
+	did, err := provider.GetDecentralizedIDForHandle(context.Background(), Handle{Domain: "example.com", Username: "carol"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, DecentralizedID(""), did)
+}
+
+func TestSQLiteProviderRejectsUnsupportedDomain(t *testing.T) {
+	provider := newTestSQLiteProvider(t)
+
+	_, err := provider.GetDecentralizedIDForHandle(context.Background(), Handle{Domain: "example.net", Username: "alice"})
+
+	assert.True(t, errors.Is(err, (*CannotGetHandelsFromDomainError)(nil)))
+}
+
+func TestSQLiteProviderCreatesTablesWithConfiguredNames(t *testing.T) {
+	provider, err := NewSQLiteHandlesProvider(filepath.Join(t.TempDir(), "handles.db"), "active_handles", "active_domains")
+	assert.Nil(t, err)
+	defer provider.Close()
+
+	canProvide, err := provider.CanProvideForDomain(context.Background(), "example.com")
+
+	assert.Nil(t, err)
+	assert.False(t, canProvide)
+}
+
+func TestSQLiteProviderManagesHandlesAndDomains(t *testing.T) {
+	provider := newTestSQLiteProvider(t)
+	ctx := context.Background()
+	bob := Handle{Domain: "example.com", Username: "bob"}
+
+	assert.Nil(t, provider.SetDecentralizedIDForHandle(ctx, bob, "did:plc:example002"))
+	assert.Nil(t, provider.SetDecentralizedIDForHandle(ctx, bob, "did:plc:example003"))
+	assert.Nil(t, provider.DeleteDecentralizedIDForHandle(ctx, Handle{Domain: "example.com", Username: "alice"}))
+
+	dids, err := provider.ListDecentralizedIDs(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, MapOfDids{"bob.example.com": "did:plc:example003"}, dids)
+
+	assert.Nil(t, provider.AddDomain(ctx, "example.net"))
+	assert.Nil(t, provider.AddDomain(ctx, "example.net"))
+	assert.Nil(t, provider.RemoveDomain(ctx, "example.com"))
+
+	domains, err := provider.ListDomains(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []Domain{"example.net"}, domains)
+}
+
+func TestSQLiteProviderProvidesForWildcardDomains(t *testing.T) {
+	provider := newTestSQLiteProvider(t)
+
+	assert.Nil(t, provider.AddDomain(context.Background(), "*.example.net"))
+
+	canProvide, err := provider.CanProvideForDomain(context.Background(), "team.example.net")
+	assert.Nil(t, err)
+	assert.True(t, canProvide)
+
+	canProvide, err = provider.CanProvideForDomain(context.Background(), "example.net")
+	assert.Nil(t, err)
+	assert.False(t, canProvide)
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// URLTemplate is rendered by replacing `{token}` or `{token|filter|...}` with
+// a value from the request, such as `{handle|urlquery}`.
+type URLTemplate string
+
+func (template *URLTemplate) UnmarshalText(text []byte) error {
+	if _, err := parseURLTemplate(string(text)); err != nil {
+		return err
+	}
+
+	*template = URLTemplate(text)
+
+	return nil
+}
+
+func (template URLTemplate) Validate() error {
+	_, err := parseURLTemplate(string(template))
+	return err
+}
+
+// ValidateHandleTokens checks a template only uses the tokens of a handle, for
+// templates which are rendered without a request.
+func (template URLTemplate) ValidateHandleTokens() error {
+	parts, err := parseURLTemplate(string(template))
+
+	if err != nil {
+		return err
+	}
+
+	for _, part := range parts {
+		if part.token != "" && part.token != "handle" && !strings.HasPrefix(part.token, "handle.") {
+			return fmt.Errorf("URL template token {%s} is not {handle}, {handle.domain} or {handle.username}", part.token)
+		}
+	}
+
+	return nil
+}
+
+// TemplateValues are the values available to tokens in a URL template.
+type TemplateValues struct {
+	Request  *http.Request
+	Handle   Handle
+	DID      DecentralizedID
+	Metadata map[string]string
+}
+
+type templateFilter func(value string, argument string) string
+
+var templateFilters = map[string]templateFilter{
+	"urlquery": func(value string, _ string) string {
+		return url.QueryEscape(value)
+	},
+	"pathescape": func(value string, _ string) string {
+		return url.PathEscape(value)
+	},
+	"default": func(value string, fallback string) string {
+		if value == "" {
+			return fallback
+		}
+
+		return value
+	},
+}
+
+var templateTokens = map[string]func(values TemplateValues) string{
+	"handle":          func(values TemplateValues) string { return values.Handle.String() },
+	"did":             func(values TemplateValues) string { return string(values.DID) },
+	"handle.domain":   func(values TemplateValues) string { return string(values.Handle.Domain) },
+	"handle.username": func(values TemplateValues) string { return string(values.Handle.Username) },
+	"request.scheme":  func(values TemplateValues) string { return values.Request.URL.Scheme },
+	"request.host":    func(values TemplateValues) string { return values.Request.Host },
+	"request.path":    func(values TemplateValues) string { return values.Request.URL.Path },
+	"request.query":   func(values TemplateValues) string { return values.Request.URL.RawQuery },
+}
+
+type templateFilterCall struct {
+	filter   templateFilter
+	argument string
+}
+
+type templatePart struct {
+	literal string
+	token   string
+	filters []templateFilterCall
+}
+
+func (part templatePart) render(values TemplateValues) string {
+	if part.token == "" {
+		return part.literal
+	}
+
+	var value string
+
+	if name, ok := strings.CutPrefix(part.token, "request.header."); ok {
+		value = values.Request.Header.Get(name)
+	} else if key, ok := strings.CutPrefix(part.token, "domain."); ok {
+		value = values.Metadata[key]
+	} else {
+		value = templateTokens[part.token](values)
+	}
+
+	for _, call := range part.filters {
+		value = call.filter(value, call.argument)
+	}
+
+	return value
+}
+
+func parseURLTemplate(template string) ([]templatePart, error) {
+	var parts []templatePart
+
+	for template != "" {
+		literal, rest, found := strings.Cut(template, "{")
+
+		if strings.Contains(literal, "}") {
+			return nil, fmt.Errorf("URL template has `}` without `{` in %q", literal)
+		}
+
+		if literal != "" {
+			parts = append(parts, templatePart{literal: literal})
+		}
+
+		if !found {
+			break
+		}
+
+		expression, remaining, closed := strings.Cut(rest, "}")
+
+		if !closed {
+			return nil, fmt.Errorf("URL template has `{` without `}` in %q", "{"+rest)
+		}
+
+		part, err := parseTemplateExpression(expression)
+
+		if err != nil {
+			return nil, fmt.Errorf("URL template token {%s} is not valid: %w", expression, err)
+		}
+
+		parts = append(parts, part)
+		template = remaining
+	}
+
+	return parts, nil
+}
+
+func parseTemplateExpression(expression string) (templatePart, error) {
+	token, filters, hasFilters := strings.Cut(expression, "|")
+
+	if !isTemplateToken(token) {
+		return templatePart{}, fmt.Errorf("%q is not a token", token)
+	}
+
+	part := templatePart{token: token}
+
+	if !hasFilters {
+		return part, nil
+	}
+
+	for _, call := range strings.Split(filters, "|") {
+		name, argument, hasArgument := strings.Cut(call, ":")
+		filter, ok := templateFilters[name]
+
+		if !ok {
+			return templatePart{}, fmt.Errorf("%q is not a filter", name)
+		}
+
+		if name == "default" && !hasArgument {
+			return templatePart{}, errors.New("the default filter needs a value (`default:value`)")
+		}
+
+		if name != "default" && hasArgument {
+			return templatePart{}, fmt.Errorf("the %s filter does not take a value", name)
+		}
+
+		part.filters = append(part.filters, templateFilterCall{filter, argument})
+	}
+
+	return part, nil
+}
+
+// isTemplateToken accepts the fixed tokens and the `request.header.` and
+// `domain.` prefixes followed by a name.
+func isTemplateToken(token string) bool {
+	for _, prefix := range []string{"request.header.", "domain."} {
+		if name, ok := strings.CutPrefix(token, prefix); ok {
+			return name != "" && !strings.ContainsAny(name, " :")
+		}
+	}
+
+	_, ok := templateTokens[token]
+
+	return ok
+}
+
+// URLFromTemplate renders a template, leaving a template which is not valid
+// unchanged (templates are validated when configuration is loaded).
+func URLFromTemplate(template URLTemplate, values TemplateValues) string {
+	parts, err := parseURLTemplate(string(template))
+
+	if err != nil {
+		return string(template)
+	}
+
+	var rendered strings.Builder
+
+	for _, part := range parts {
+		rendered.WriteString(part.render(values))
+	}
+
+	return rendered.String()
+}
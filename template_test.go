@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplateUrlIsFormatted(t *testing.T) {
+	tests := []struct {
+		template    URLTemplate
+		expectedUrl string
+	}{
+		{
+			template:    "https://example.com/?handle={handle}",
+			expectedUrl: "https://example.com/?handle=alice.example.com",
+		},
+		{
+			template:    "https://{handle.domain}/?username={handle.username}",
+			expectedUrl: "https://example.com/?username=alice",
+		},
+		{
+			template:    "https://bsky.app/profile/{did}",
+			expectedUrl: "https://bsky.app/profile/did:plc:example",
+		},
+		{
+			template:    "https://example.com",
+			expectedUrl: "https://example.com",
+		},
+		{
+			template:    "https://example.com?{request.query}",
+			expectedUrl: "https://example.com?a=b",
+		},
+		{
+			template:    "https://example.com/?next={request.path|urlquery}",
+			expectedUrl: "https://example.com/?next=%2Fhello+world%2F",
+		},
+		{
+			template:    "https://example.com/{request.path|pathescape}",
+			expectedUrl: "https://example.com/%2Fhello%20world%2F",
+		},
+		{
+			template:    "https://example.com/{request.header.Accept-Language|default:en}",
+			expectedUrl: "https://example.com/en",
+		},
+		{
+			template:    "https://example.com/?ref={request.header.Referer|urlquery}",
+			expectedUrl: "https://example.com/?ref=https%3A%2F%2Fexample.net%2F",
+		},
+		{
+			template:    "https://{domain.community}.example.com/{domain.missing|default:home}",
+			expectedUrl: "https://gardeners.example.com/home",
+		},
+	}
+
+	request, _ := http.NewRequest("GET", "https://alice.example.com/hello%20world/?a=b", bytes.NewReader([]byte{}))
+	request.Header.Set("Referer", "https://example.net/")
+
+	values := TemplateValues{
+		Request:  request,
+		Handle:   Handle{Domain: "example.com", Username: "alice"},
+		DID:      DecentralizedID("did:plc:example"),
+		Metadata: map[string]string{"community": "gardeners"},
+	}
+
+	for _, test := range tests {
+		url := URLFromTemplate(test.template, values)
+		assert.Equal(
+			t,
+			test.expectedUrl,
+			url,
+			"Template %s was not formatted correctly",
+			test.template,
+		)
+	}
+}
+
+func TestTemplateIsValidated(t *testing.T) {
+	tests := []struct {
+		template URLTemplate
+		valid    bool
+	}{
+		{template: "https://example.com/{handle}", valid: true},
+		{template: "https://example.com/{did|default:none|urlquery}", valid: true},
+		{template: "https://example.com/{request.header.X-Forwarded-For}", valid: true},
+		{template: "https://example.com/{domain.community}", valid: true},
+		{template: "https://example.com/{handle.unknown}", valid: false},
+		{template: "https://example.com/{handle|shout}", valid: false},
+		{template: "https://example.com/{handle|urlquery:yes}", valid: false},
+		{template: "https://example.com/{did|default}", valid: false},
+		{template: "https://example.com/{handle", valid: false},
+		{template: "https://example.com/handle}", valid: false},
+		{template: "https://example.com/{domain.}", valid: false},
+	}
+
+	for _, test := range tests {
+		var template URLTemplate
+
+		err := template.UnmarshalText([]byte(test.template))
+
+		if test.valid {
+			assert.NoError(t, err, test.template)
+			assert.Equal(t, test.template, template)
+		} else {
+			assert.Error(t, err, test.template)
+		}
+	}
+}
+
+func TestHandleTemplatesOnlyUseHandleTokens(t *testing.T) {
+	assert.NoError(t, URLTemplate("https://example.com/{handle.username|pathescape}?domain={handle.domain}&handle={handle}").ValidateHandleTokens())
+	assert.Error(t, URLTemplate("https://example.com/{did}").ValidateHandleTokens())
+	assert.Error(t, URLTemplate("https://{request.host}").ValidateHandleTokens())
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/fsnotify/fsnotify"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+type mapOfCertificates map[string]*tls.Certificate
+
+// CertificateDirectory holds the certificates of a directory of PEM encoded
+// certificate (`<name>.crt`) and key (`<name>.key`) pairs, indexed by the names
+// each certificate is valid for, and reloads them when the directory changes.
+type CertificateDirectory struct {
+	path         string
+	logger       *slog.Logger
+	watcher      *fsnotify.Watcher
+	certificates atomic.Pointer[mapOfCertificates]
+}
+
+func NewCertificateDirectory(path string, logger *slog.Logger) (*CertificateDirectory, error) {
+	directory := &CertificateDirectory{path: filepath.Clean(path), logger: logger}
+
+	if err := directory.reload(); err != nil {
+		return nil, err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := watcher.Add(directory.path); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	directory.watcher = watcher
+
+	go directory.watch()
+
+	return directory, nil
+}
+
+// Certificate finds the certificate for a hostname, falling back to a
+// wildcard certificate for its parent domain.
+func (directory *CertificateDirectory) Certificate(hostname string) (*tls.Certificate, bool) {
+	certificates := *directory.certificates.Load()
+
+	if certificate, ok := certificates[hostname]; ok {
+		return certificate, true
+	}
+
+	if _, parent, ok := strings.Cut(hostname, "."); ok {
+		certificate, ok := certificates["*."+parent]
+		return certificate, ok
+	}
+
+	return nil, false
+}
+
+func (directory *CertificateDirectory) Close() error {
+	return directory.watcher.Close()
+}
+
+func (directory *CertificateDirectory) watch() {
+	for {
+		select {
+		case event, ok := <-directory.watcher.Events:
+			if !ok {
+				return
+			}
+
+			if !isCertificateFile(event.Name) {
+				continue
+			}
+
+			if err := directory.reload(); err != nil {
+				directory.logger.Error("could not reload certificates, continuing with previous certificates", "path", directory.path, "error", err)
+				continue
+			}
+
+			directory.logger.Info("reloaded certificates", "path", directory.path)
+		case err, ok := <-directory.watcher.Errors:
+			if !ok {
+				return
+			}
+
+			directory.logger.Error("error watching certificates", "path", directory.path, "error", err)
+		}
+	}
+}
+
+func (directory *CertificateDirectory) reload() error {
+	certificates, err := LoadCertificateDirectory(directory.path)
+
+	if err != nil {
+		return err
+	}
+
+	directory.certificates.Store(&certificates)
+
+	return nil
+}
+
+func isCertificateFile(path string) bool {
+	extension := filepath.Ext(path)
+	return extension == ".crt" || extension == ".key"
+}
+
+func LoadCertificateDirectory(path string) (mapOfCertificates, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("could not read certificates directory: %w", err)
+	}
+
+	certificateFiles, err := filepath.Glob(filepath.Join(path, "*.crt"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	certificates := make(mapOfCertificates)
+
+	for _, certificateFile := range certificateFiles {
+		keyFile := strings.TrimSuffix(certificateFile, ".crt") + ".key"
+
+		certificate, err := tls.LoadX509KeyPair(certificateFile, keyFile)
+
+		if err != nil {
+			return nil, fmt.Errorf("could not load certificate %s: %w", certificateFile, err)
+		}
+
+		if certificate.Leaf == nil {
+			if certificate.Leaf, err = x509.ParseCertificate(certificate.Certificate[0]); err != nil {
+				return nil, fmt.Errorf("could not parse certificate %s: %w", certificateFile, err)
+			}
+		}
+
+		if len(certificate.Leaf.DNSNames) == 0 {
+			return nil, fmt.Errorf("certificate %s is not valid for any DNS names", certificateFile)
+		}
+
+		for _, name := range certificate.Leaf.DNSNames {
+			certificates[strings.ToLower(name)] = &certificate
+		}
+	}
+
+	return certificates, nil
+}
+
+// RequireProvidedDomain refuses handshakes for hostnames which are not handles
+// on a domain supported by the provider before getting a certificate.
+func RequireProvidedDomain(provider ProvidesDecentralizedIDs, getCertificate func(*tls.ClientHelloInfo) (*tls.Certificate, error)) func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		if hello.ServerName == "" {
+			return nil, errors.New("refusing handshake without server name")
+		}
+
+		handle, err := HostnameToHandle(strings.ToLower(HostToHostname(hello.ServerName)))
+
+		if err != nil {
+			return nil, fmt.Errorf("refusing handshake for %s: %w", hello.ServerName, err)
+		}
+
+		if handle, err = HandleOnProvidedDomain(hello.Context(), provider, handle); err != nil {
+			return nil, err
+		}
+
+		canProvide, err := provider.CanProvideForDomain(hello.Context(), handle.Domain)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !canProvide {
+			return nil, fmt.Errorf("refusing handshake for %s: %w", hello.ServerName, &CannotGetHandelsFromDomainError{handle.Domain})
+		}
+
+		return getCertificate(hello)
+	}
+}
+
+// NewTLSConfig uses a certificate from the directory when there is one and
+// otherwise issues one using ACME; either may be nil.
+func NewTLSConfig(provider ProvidesDecentralizedIDs, certificates *CertificateDirectory, manager *autocert.Manager) *tls.Config {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: RequireProvidedDomain(provider, func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			hostname := strings.ToLower(HostToHostname(hello.ServerName))
+
+			if certificates != nil {
+				if certificate, ok := certificates.Certificate(hostname); ok {
+					return certificate, nil
+				}
+			}
+
+			if manager != nil {
+				return manager.GetCertificate(hello)
+			}
+
+			return nil, fmt.Errorf("no certificate for %s", hostname)
+		}),
+	}
+
+	if manager != nil {
+		config.NextProtos = []string{"h2", "http/1.1", acme.ALPNProto}
+	}
+
+	return config
+}
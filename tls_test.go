@@ -0,0 +1,150 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTestCertificate(t *testing.T, directory string, name string, dnsNames ...string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+
+	privateKey, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+
+	// The key is written first so that the pair is complete when the
+	// certificate appears.
+	assert.NoError(t, os.WriteFile(filepath.Join(directory, name+".key"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateKey}), 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(directory, name+".crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate}), 0o600))
+}
+
+func newTestTLSConfig(t *testing.T, directory string) *tls.Config {
+	certificates, err := NewCertificateDirectory(directory, testFileLogger)
+	assert.NoError(t, err)
+	t.Cleanup(func() { certificates.Close() })
+
+	return NewTLSConfig(NewInMemoryProvider(MapOfDids{}, MapOfDomains{
+		"example.com": true,
+		"example.org": true,
+		"example.net": true,
+	}), certificates, nil)
+}
+
+func certificateNameFor(t *testing.T, config *tls.Config, serverName string) (string, error) {
+	certificate, err := config.GetCertificate(&tls.ClientHelloInfo{ServerName: serverName})
+
+	if err != nil {
+		return "", err
+	}
+
+	return certificate.Leaf.Subject.CommonName, nil
+}
+
+func TestCertificateIsChosenByServerName(t *testing.T) {
+	directory := t.TempDir()
+	writeTestCertificate(t, directory, "alice", "alice.example.com")
+	writeTestCertificate(t, directory, "wildcard", "*.example.com", "example.com")
+	writeTestCertificate(t, directory, "org", "*.example.org")
+
+	config := newTestTLSConfig(t, directory)
+
+	tests := map[string]string{
+		"alice.example.com": "alice.example.com",
+		"ALICE.example.com": "alice.example.com",
+		"bob.example.com":   "*.example.com",
+		"carol.example.org": "*.example.org",
+	}
+
+	for serverName, expected := range tests {
+		name, err := certificateNameFor(t, config, serverName)
+
+		assert.NoError(t, err, serverName)
+		assert.Equal(t, expected, name, serverName)
+	}
+}
+
+func TestHandshakeIsRefusedForUnprovidedDomainsAndUnknownNames(t *testing.T) {
+	directory := t.TempDir()
+	writeTestCertificate(t, directory, "wildcard", "*.example.com", "*.example.test")
+
+	config := newTestTLSConfig(t, directory)
+
+	for _, serverName := range []string{"", "alice.example.test", "localhost", "alice.example.net", "alice.team.example.com"} {
+		_, err := certificateNameFor(t, config, serverName)
+
+		assert.Error(t, err, "handshake for %q was not refused", serverName)
+	}
+}
+
+func TestCertificatesAreReloadedWhenDirectoryChanges(t *testing.T) {
+	directory := t.TempDir()
+	writeTestCertificate(t, directory, "alice", "alice.example.com")
+
+	config := newTestTLSConfig(t, directory)
+
+	_, err := certificateNameFor(t, config, "bob.example.com")
+	assert.Error(t, err)
+
+	writeTestCertificate(t, directory, "bob", "bob.example.com")
+
+	assert.Eventually(t, func() bool {
+		name, _ := certificateNameFor(t, config, "bob.example.com")
+		return name == "bob.example.com"
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestCertificateDirectoryMustContainCompletePairs(t *testing.T) {
+	directory := t.TempDir()
+	writeTestCertificate(t, directory, "alice", "alice.example.com")
+	assert.NoError(t, os.Remove(filepath.Join(directory, "alice.key")))
+
+	_, err := NewCertificateDirectory(directory, testFileLogger)
+
+	assert.Error(t, err)
+}
+
+func TestCertificateDirectoryIsCheckedWithConfiguration(t *testing.T) {
+	directory := t.TempDir()
+
+	t.Setenv("DID_PROVIDER", "memory")
+	t.Setenv("MEMORY_DIDS", "alice.example.com@did:plc:example001")
+	t.Setenv("MEMORY_DOMAINS", "example.com")
+	t.Setenv("TLS_ADDRESS", ":8443")
+	t.Setenv("TLS_CERTIFICATES_PATH", directory)
+
+	_, err := ConfigFromEnvironment()
+	assert.NoError(t, err)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(directory, "example.com.crt"), []byte("not a certificate"), 0o600))
+
+	_, err = ConfigFromEnvironment()
+	assert.Error(t, err)
+
+	t.Setenv("TLS_CERTIFICATES_PATH", filepath.Join(directory, "missing"))
+
+	_, err = ConfigFromEnvironment()
+	assert.Error(t, err)
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "handles-server"
+
+// tracer is looked up on every use so that spans go to whichever tracer
+// provider is currently installed globally.
+func tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// NewTracerProvider exports spans using OTLP over HTTP, configured by the
+// standard `OTEL_EXPORTER_OTLP_*` and `OTEL_SERVICE_NAME` variables, and
+// installs it globally.
+func NewTracerProvider(ctx context.Context) (*sdktrace.TracerProvider, error) {
+	exporter, err := otlptracehttp.New(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not create OTLP exporter: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter))
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return provider, nil
+}
+
+func handleAttributes(handle Handle) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("handles.handle", handle.String()),
+		attribute.String("handles.domain", string(handle.Domain)),
+	}
+}
+
+// TraceRequests starts a server span for every request, continuing any trace
+// propagated by the client.
+func TraceRequests(c *gin.Context) {
+	ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+	ctx, span := tracer().Start(ctx, c.Request.Method, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+		attribute.String("http.request.method", c.Request.Method),
+		attribute.String("server.address", HostToHostname(c.Request.Host)),
+		attribute.String("url.path", c.Request.URL.Path),
+	))
+	defer span.End()
+
+	c.Request = c.Request.WithContext(ctx)
+
+	c.Next()
+
+	if route := c.FullPath(); route != "" {
+		span.SetName(c.Request.Method + " " + route)
+		span.SetAttributes(attribute.String("http.route", route))
+	}
+
+	span.SetAttributes(attribute.Int("http.response.status_code", c.Writer.Status()))
+
+	if handle, ok := c.Get("handle"); ok {
+		span.SetAttributes(handleAttributes(handle.(Handle))...)
+	}
+
+	if result, ok := c.Get("result"); ok {
+		span.SetAttributes(attribute.String("handles.outcome", ResolutionOutcome(result.(Result).DecentralizedID, nil)))
+	}
+
+	if c.Writer.Status() >= 500 {
+		span.SetStatus(codes.Error, c.Errors.String())
+	}
+}
+
+// Traced wraps a handler in a span of its own, so that each step of the
+// middleware chain can be seen within the request.
+func Traced(name string, handler gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		parent := c.Request
+
+		ctx, span := tracer().Start(parent.Context(), name)
+		defer span.End()
+
+		c.Request = parent.WithContext(ctx)
+
+		handler(c)
+
+		c.Request = parent
+
+		if handle, ok := c.Get("handle"); ok {
+			span.SetAttributes(handleAttributes(handle.(Handle))...)
+		}
+	}
+}
+
+// TracedProvider records a span for every call to the provider.
+type TracedProvider struct {
+	provider ProvidesDecentralizedIDs
+}
+
+func NewTracedProvider(provider ProvidesDecentralizedIDs) *TracedProvider {
+	return &TracedProvider{provider}
+}
+
+func (traced *TracedProvider) GetDecentralizedIDForHandle(ctx context.Context, handle Handle) (DecentralizedID, error) {
+	ctx, span := tracer().Start(ctx, "GetDecentralizedIDForHandle", trace.WithAttributes(handleAttributes(handle)...))
+	defer span.End()
+
+	did, err := traced.provider.GetDecentralizedIDForHandle(ctx, handle)
+
+	outcome := ResolutionOutcome(did, err)
+
+	span.SetAttributes(attribute.String("handles.outcome", outcome))
+
+	if did != "" {
+		span.SetAttributes(attribute.String("handles.did", string(did)))
+	}
+
+	if outcome == "error" {
+		recordSpanError(span, err)
+	}
+
+	return did, err
+}
+
+func (traced *TracedProvider) CanProvideForDomain(ctx context.Context, domain Domain) (bool, error) {
+	ctx, span := tracer().Start(ctx, "CanProvideForDomain", trace.WithAttributes(
+		attribute.String("handles.domain", string(domain)),
+	))
+	defer span.End()
+
+	canProvide, err := traced.provider.CanProvideForDomain(ctx, domain)
+
+	span.SetAttributes(attribute.Bool("handles.can_provide", canProvide))
+
+	recordSpanError(span, err)
+
+	return canProvide, err
+}
+
+func (traced *TracedProvider) IsHealthy(ctx context.Context) (bool, string) {
+	ctx, span := tracer().Start(ctx, "IsHealthy")
+	defer span.End()
+
+	healthy, explanation := traced.provider.IsHealthy(ctx)
+
+	if !healthy {
+		span.SetStatus(codes.Error, explanation)
+	}
+
+	return healthy, explanation
+}
+
+func (traced *TracedProvider) Unwrap() ProvidesDecentralizedIDs {
+	return traced.provider
+}
+
+func (traced *TracedProvider) Close() error {
+	if closer, ok := traced.provider.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+// PostgresTracer records a span for every query, alongside the query logging
+// of `tracelog`.
+type PostgresTracer struct{}
+
+func (PostgresTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	ctx, _ = tracer().Start(ctx, "postgres query", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("db.system", "postgresql"),
+		attribute.String("db.query.text", data.SQL),
+	))
+
+	return ctx
+}
+
+func (PostgresTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("db.response.rows_affected", data.CommandTag.RowsAffected()))
+
+	recordSpanError(span, data.Err)
+}
+
+func recordSpanError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func NewTestSpanExporter(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	previous := otel.GetTracerProvider()
+
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	return exporter
+}
+
+func findSpan(spans tracetest.SpanStubs, name string) (tracetest.SpanStub, bool) {
+	for _, span := range spans {
+		if span.Name == name {
+			return span, true
+		}
+	}
+
+	return tracetest.SpanStub{}, false
+}
+
+func spanAttribute(span tracetest.SpanStub, key attribute.Key) string {
+	for _, attribute := range span.Attributes {
+		if attribute.Key == key {
+			return attribute.Value.Emit()
+		}
+	}
+
+	return ""
+}
+
+func TestTracingRecordsSpansForMiddlewareChainAndProvider(t *testing.T) {
+	exporter := NewTestSpanExporter(t)
+
+	router := gin.New()
+	AddApplicationRoutes(router, Config{
+		Provider: NewTracedProvider(NewInMemoryProvider(map[Hostname]DecentralizedID{
+			"alice.example.com": "did:plc:example001",
+		}, map[Domain]bool{
+			"example.com": true,
+		})),
+		Logger:         testFileLogger,
+		TracingEnabled: true,
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/.well-known/atproto-did", nil)
+	req.Host = "alice.example.com"
+	router.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	spans := exporter.GetSpans()
+	request, ok := findSpan(spans, "GET /.well-known/atproto-did")
+
+	assert.True(t, ok, "request span was not recorded")
+	assert.Equal(t, "alice.example.com", spanAttribute(request, "handles.handle"))
+	assert.Equal(t, "found", spanAttribute(request, "handles.outcome"))
+
+	for _, name := range []string{"ParseHandleFromHostname", "WithHandleResult", "VerifyHandle"} {
+		span, ok := findSpan(spans, name)
+
+		assert.True(t, ok, "%s span was not recorded", name)
+		assert.Equal(t, request.SpanContext.TraceID(), span.SpanContext.TraceID(), "%s span is not part of the request trace", name)
+	}
+
+	provider, ok := findSpan(spans, "GetDecentralizedIDForHandle")
+	parent, _ := findSpan(spans, "WithHandleResult")
+
+	assert.True(t, ok, "provider span was not recorded")
+	assert.Equal(t, parent.SpanContext.SpanID(), provider.Parent.SpanID())
+	assert.Equal(t, "alice.example.com", spanAttribute(provider, "handles.handle"))
+	assert.Equal(t, "example.com", spanAttribute(provider, "handles.domain"))
+	assert.Equal(t, "found", spanAttribute(provider, "handles.outcome"))
+}
+
+func TestTracedProviderRecordsOutcomes(t *testing.T) {
+	tests := []struct {
+		handle  Handle
+		outcome string
+	}{
+		{handle: Handle{Domain: "example.com", Username: "alice"}, outcome: "found"},
+		{handle: Handle{Domain: "example.com", Username: "bob"}, outcome: "not_found"},
+		{handle: Handle{Domain: "example.net", Username: "alice"}, outcome: "unsupported_domain"},
+	}
+
+	exporter := NewTestSpanExporter(t)
+	provider := NewTracedProvider(NewInMemoryProvider(map[Hostname]DecentralizedID{
+		"alice.example.com": "did:plc:example001",
+	}, map[Domain]bool{
+		"example.com": true,
+	}))
+
+	for _, test := range tests {
+		exporter.Reset()
+
+		_, _ = provider.GetDecentralizedIDForHandle(context.Background(), test.handle)
+
+		spans := exporter.GetSpans()
+
+		assert.Len(t, spans, 1)
+		assert.Equal(t, test.outcome, spanAttribute(spans[0], "handles.outcome"), "%s", test.handle)
+	}
+}
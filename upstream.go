@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// UpstreamAPI is the response format of an upstream server.
+type UpstreamAPI string
+
+const (
+	// UpstreamWellKnown reads a DID from the text of `/.well-known/atproto-did`.
+	UpstreamWellKnown UpstreamAPI = "well-known"
+	// UpstreamXRPC reads a DID from the JSON of `com.atproto.identity.resolveHandle`.
+	UpstreamXRPC UpstreamAPI = "xrpc"
+)
+
+func (api *UpstreamAPI) UnmarshalText(text []byte) error {
+	switch UpstreamAPI(text) {
+	case UpstreamWellKnown, UpstreamXRPC:
+		*api = UpstreamAPI(text)
+		return nil
+	default:
+		return fmt.Errorf("%q is not well-known or xrpc", text)
+	}
+}
+
+var ErrCircuitOpen = errors.New("upstream circuit breaker is open")
+
+// CircuitBreaker stops requests to an upstream server after a number of
+// consecutive failures, allowing a single request to try again once the
+// cooldown has passed. A threshold of zero never stops requests.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mutex     sync.Mutex
+	failures  int
+	openedAt  time.Time
+	trying    bool
+	lastError error
+}
+
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+func (breaker *CircuitBreaker) isOpen() bool {
+	return breaker.threshold > 0 && breaker.failures >= breaker.threshold
+}
+
+// Allow fails while the breaker is open, and otherwise must be followed by a
+// call to Record with the result of the request.
+func (breaker *CircuitBreaker) Allow() error {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	if !breaker.isOpen() {
+		return nil
+	}
+
+	if breaker.trying || time.Since(breaker.openedAt) < breaker.cooldown {
+		return fmt.Errorf("%w after %d failures: %w", ErrCircuitOpen, breaker.failures, breaker.lastError)
+	}
+
+	breaker.trying = true
+
+	return nil
+}
+
+// Record counts a failed request, or closes the breaker after a request which
+// succeeded. Requests cancelled by the caller are not counted.
+func (breaker *CircuitBreaker) Record(err error) {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	breaker.trying = false
+
+	switch {
+	case err == nil:
+		breaker.failures = 0
+		breaker.lastError = nil
+	case errors.Is(err, context.Canceled):
+		return
+	default:
+		breaker.failures++
+		breaker.lastError = err
+
+		if breaker.isOpen() {
+			breaker.openedAt = time.Now()
+		}
+	}
+}
+
+// Status is unhealthy while the breaker is open.
+func (breaker *CircuitBreaker) Status() (bool, string) {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	switch {
+	case breaker.isOpen():
+		retry := max(time.Until(breaker.openedAt.Add(breaker.cooldown)), 0).Round(time.Second)
+		return false, fmt.Sprintf("circuit open after %d failures, retrying in %s (%s)", breaker.failures, retry, breaker.lastError)
+	case breaker.failures > 0:
+		return true, fmt.Sprintf("circuit closed, %d recent failures (%s)", breaker.failures, breaker.lastError)
+	default:
+		return true, "circuit closed"
+	}
+}
+
+// UpstreamProvider resolves handles by asking another server, such as another
+// handles server or a PDS, retrying requests which fail.
+type UpstreamProvider struct {
+	urlTemplate  URLTemplate
+	hostTemplate URLTemplate
+	api          UpstreamAPI
+	domains      MapOfDomains
+	client       *http.Client
+	retries      int
+	retryDelay   time.Duration
+	breaker      *CircuitBreaker
+}
+
+// NewUpstreamProvider provides for the given domains, or every domain when
+// domains is nil. The host template overrides the `Host` header of requests.
+func NewUpstreamProvider(urlTemplate URLTemplate, hostTemplate URLTemplate, api UpstreamAPI, domains MapOfDomains, client *http.Client, retries int, retryDelay time.Duration, breaker *CircuitBreaker) *UpstreamProvider {
+	return &UpstreamProvider{
+		urlTemplate:  urlTemplate,
+		hostTemplate: hostTemplate,
+		api:          api,
+		domains:      domains,
+		client:       client,
+		retries:      retries,
+		retryDelay:   retryDelay,
+		breaker:      breaker,
+	}
+}
+
+func (upstream *UpstreamProvider) GetDecentralizedIDForHandle(ctx context.Context, handle Handle) (DecentralizedID, error) {
+	canProvide, _ := upstream.CanProvideForDomain(ctx, handle.Domain)
+
+	if !canProvide {
+		return "", &CannotGetHandelsFromDomainError{domain: handle.Domain}
+	}
+
+	if err := upstream.breaker.Allow(); err != nil {
+		return "", err
+	}
+
+	did, fault, err := upstream.resolveWithRetries(ctx, handle)
+
+	// Answers from the upstream server, even errors, show that it is working.
+	if fault {
+		upstream.breaker.Record(err)
+	} else {
+		upstream.breaker.Record(nil)
+	}
+
+	return did, err
+}
+
+func (upstream *UpstreamProvider) CanProvideForDomain(ctx context.Context, domain Domain) (bool, error) {
+	if upstream.domains == nil {
+		return true, nil
+	}
+
+	for _, pattern := range DomainPatterns(domain) {
+		if upstream.domains[pattern] {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (upstream *UpstreamProvider) IsHealthy(ctx context.Context) (bool, string) {
+	healthy, status := upstream.breaker.Status()
+
+	return healthy, fmt.Sprintf("Resolving with %s, %s", upstream.urlTemplate, status)
+}
+
+// resolveWithRetries waits twice as long before each retry, reporting whether
+// the last failure was a fault of the upstream server.
+func (upstream *UpstreamProvider) resolveWithRetries(ctx context.Context, handle Handle) (DecentralizedID, bool, error) {
+	delay := upstream.retryDelay
+
+	for attempt := 0; ; attempt++ {
+		did, retry, err := upstream.resolve(ctx, handle)
+
+		if err == nil || !retry || attempt >= upstream.retries {
+			return did, retry, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", true, ctx.Err()
+		case <-time.After(delay):
+			delay *= 2
+		}
+	}
+}
+
+// resolve makes a single request, reporting whether a failure can be retried:
+// requests which fail to connect, or which the upstream server fails with a
+// server error or rate limit. A handle which the upstream server does not
+// have, or rejects (`400 Bad Request`) as not valid or on a domain it does not
+// support, has no Decentralized ID.
+func (upstream *UpstreamProvider) resolve(ctx context.Context, handle Handle) (DecentralizedID, bool, error) {
+	values := TemplateValues{Handle: handle}
+	location := URLFromTemplate(upstream.urlTemplate, values)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+
+	if err != nil {
+		return "", false, err
+	}
+
+	if upstream.hostTemplate != "" {
+		request.Host = URLFromTemplate(upstream.hostTemplate, values)
+	}
+
+	response, err := upstream.client.Do(request)
+
+	if err != nil {
+		return "", true, err
+	}
+
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, 1<<16))
+
+	if err != nil {
+		return "", true, err
+	}
+
+	switch {
+	case response.StatusCode >= http.StatusInternalServerError || response.StatusCode == http.StatusTooManyRequests:
+		return "", true, fmt.Errorf("upstream %s responded %s", location, response.Status)
+	case response.StatusCode == http.StatusNotFound || response.StatusCode == http.StatusBadRequest:
+		return "", false, nil
+	case response.StatusCode != http.StatusOK:
+		return "", false, fmt.Errorf("upstream %s responded %s", location, response.Status)
+	}
+
+	did := DecentralizedID(strings.TrimSpace(string(body)))
+
+	if upstream.api == UpstreamXRPC {
+		var resolved struct {
+			DID DecentralizedID `json:"did"`
+		}
+
+		if err := json.Unmarshal(body, &resolved); err != nil {
+			return "", false, fmt.Errorf("upstream %s responded with invalid JSON: %w", location, err)
+		}
+
+		did = resolved.DID
+	}
+
+	if err := ValidateDecentralizedID(did); err != nil {
+		return "", false, fmt.Errorf("upstream %s responded with an invalid DID: %w", location, err)
+	}
+
+	return did, false, nil
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestUpstreamProvider(server *httptest.Server, urlTemplate URLTemplate, api UpstreamAPI, breaker *CircuitBreaker) *UpstreamProvider {
+	return NewUpstreamProvider(urlTemplate, "{handle}", api, nil, server.Client(), 2, time.Millisecond, breaker)
+}
+
+func TestUpstreamProviderResolvesHandlesFromWellKnownEndpoint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/.well-known/atproto-did", r.URL.Path)
+
+		if r.Host != "alice.example.com" {
+			http.NotFound(w, r)
+			return
+		}
+
+		_, _ = w.Write([]byte("did:plc:example001\n"))
+	}))
+	defer server.Close()
+
+	upstream := newTestUpstreamProvider(server, URLTemplate(server.URL+"/.well-known/atproto-did"), UpstreamWellKnown, NewCircuitBreaker(5, time.Minute))
+
+	did, err := upstream.GetDecentralizedIDForHandle(context.Background(), Handle{Username: "alice", Domain: "example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, DecentralizedID("did:plc:example001"), did)
+
+	did, err = upstream.GetDecentralizedIDForHandle(context.Background(), Handle{Username: "bob", Domain: "example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, DecentralizedID(""), did)
+}
+
+func TestUpstreamProviderResolvesHandlesFromXRPC(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("handle") {
+		case "alice.example.com":
+			_, _ = w.Write([]byte(`{"did": "did:plc:example001"}`))
+		case "bob.example.com":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error": "InvalidRequest", "message": "Unable to resolve handle"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error": "InvalidRequest", "message": "Invalid handle"}`))
+		}
+	}))
+	defer server.Close()
+
+	upstream := newTestUpstreamProvider(server, URLTemplate(server.URL+"/xrpc/com.atproto.identity.resolveHandle?handle={handle|urlquery}"), UpstreamXRPC, NewCircuitBreaker(5, time.Minute))
+
+	did, err := upstream.GetDecentralizedIDForHandle(context.Background(), Handle{Username: "alice", Domain: "example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, DecentralizedID("did:plc:example001"), did)
+
+	did, err = upstream.GetDecentralizedIDForHandle(context.Background(), Handle{Username: "bob", Domain: "example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, DecentralizedID(""), did)
+
+	did, err = upstream.GetDecentralizedIDForHandle(context.Background(), Handle{Username: "carol", Domain: "example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, DecentralizedID(""), did)
+}
+
+func TestUpstreamProviderRetriesServerErrors(t *testing.T) {
+	var requests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		_, _ = w.Write([]byte("did:plc:example001"))
+	}))
+	defer server.Close()
+
+	upstream := newTestUpstreamProvider(server, URLTemplate(server.URL), UpstreamWellKnown, NewCircuitBreaker(5, time.Minute))
+
+	did, err := upstream.GetDecentralizedIDForHandle(context.Background(), Handle{Username: "alice", Domain: "example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, DecentralizedID("did:plc:example001"), did)
+	assert.Equal(t, int32(3), requests.Load())
+}
+
+func TestUpstreamProviderDoesNotRetryInvalidResponses(t *testing.T) {
+	var requests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		_, _ = w.Write([]byte("<html>not a DID</html>"))
+	}))
+	defer server.Close()
+
+	upstream := newTestUpstreamProvider(server, URLTemplate(server.URL), UpstreamWellKnown, NewCircuitBreaker(5, time.Minute))
+
+	_, err := upstream.GetDecentralizedIDForHandle(context.Background(), Handle{Username: "alice", Domain: "example.com"})
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestUpstreamProviderOpensCircuitAfterFailures(t *testing.T) {
+	var failing atomic.Bool
+	var requests atomic.Int32
+
+	failing.Store(true)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		if failing.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		_, _ = w.Write([]byte("did:plc:example001"))
+	}))
+	defer server.Close()
+
+	upstream := newTestUpstreamProvider(server, URLTemplate(server.URL), UpstreamWellKnown, NewCircuitBreaker(2, 50*time.Millisecond))
+	handle := Handle{Username: "alice", Domain: "example.com"}
+
+	for range 2 {
+		_, err := upstream.GetDecentralizedIDForHandle(context.Background(), handle)
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrCircuitOpen)
+	}
+
+	assert.Equal(t, int32(6), requests.Load())
+
+	healthy, status := upstream.IsHealthy(context.Background())
+	assert.False(t, healthy)
+	assert.Contains(t, status, "circuit open after 2 failures")
+
+	_, err := upstream.GetDecentralizedIDForHandle(context.Background(), handle)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, int32(6), requests.Load())
+
+	failing.Store(false)
+	time.Sleep(60 * time.Millisecond)
+
+	did, err := upstream.GetDecentralizedIDForHandle(context.Background(), handle)
+	assert.NoError(t, err)
+	assert.Equal(t, DecentralizedID("did:plc:example001"), did)
+
+	healthy, _ = upstream.IsHealthy(context.Background())
+	assert.True(t, healthy)
+}
+
+func TestUpstreamProviderDoesNotOpenCircuitForRejectedRequests(t *testing.T) {
+	var requests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		if r.Host == "alice.example.net" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	upstream := newTestUpstreamProvider(server, URLTemplate(server.URL), UpstreamWellKnown, NewCircuitBreaker(2, time.Minute))
+
+	for range 3 {
+		did, err := upstream.GetDecentralizedIDForHandle(context.Background(), Handle{Username: "alice", Domain: "example.org"})
+		assert.NoError(t, err)
+		assert.Equal(t, DecentralizedID(""), did)
+
+		_, err = upstream.GetDecentralizedIDForHandle(context.Background(), Handle{Username: "alice", Domain: "example.net"})
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrCircuitOpen)
+	}
+
+	assert.Equal(t, int32(6), requests.Load())
+
+	healthy, _ := upstream.IsHealthy(context.Background())
+	assert.True(t, healthy)
+}
+
+func TestCircuitBreakerAllowsOneRequestAfterCooldown(t *testing.T) {
+	breaker := NewCircuitBreaker(1, 0)
+	breaker.Record(assert.AnError)
+
+	assert.NoError(t, breaker.Allow())
+	assert.ErrorIs(t, breaker.Allow(), ErrCircuitOpen)
+
+	breaker.Record(assert.AnError)
+
+	assert.NoError(t, breaker.Allow())
+	breaker.Record(nil)
+
+	assert.NoError(t, breaker.Allow())
+	assert.NoError(t, breaker.Allow())
+}
+
+func TestUpstreamProviderOnlyProvidesForItsDomains(t *testing.T) {
+	upstream := NewUpstreamProvider("https://upstream.example.com/{handle}", "", UpstreamWellKnown, MapOfDomains{"example.com": true}, http.DefaultClient, 0, 0, NewCircuitBreaker(0, 0))
+
+	_, err := upstream.GetDecentralizedIDForHandle(context.Background(), Handle{Username: "alice", Domain: "example.net"})
+	assert.ErrorIs(t, err, (*CannotGetHandelsFromDomainError)(nil))
+
+	canProvide, _ := upstream.CanProvideForDomain(context.Background(), "example.com")
+	assert.True(t, canProvide)
+}
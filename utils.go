@@ -1,51 +1,96 @@
 package main
 
 import (
+	"errors"
 	"fmt"
-	"net/http"
+	"net"
 	"regexp"
+	"slices"
 	"strings"
 )
 
 func HostnameToHandle(hostname string) (Handle, error) {
-	domainParser := regexp.MustCompile(`^(.+?)\.(.+)$`)
+	hostname = strings.ToLower(hostname)
 
-	parts := domainParser.FindStringSubmatch(strings.ToLower(hostname))
-
-	if len(parts) != 3 {
-		return Handle{}, fmt.Errorf("Handle could not be parsed from hostname %s", hostname)
+	if err := ValidateHostname(hostname); err != nil {
+		return Handle{}, fmt.Errorf("Handle %s is not valid: %w", hostname, err)
 	}
 
+	username, domain, _ := strings.Cut(hostname, ".")
+
 	return Handle{
-		Domain:   Domain(parts[2]),
-		Username: Username(parts[1]),
+		Domain:   Domain(domain),
+		Username: Username(username),
 	}, nil
 }
 
-type URLTemplate string
+// HostToHostname removes the port and trailing dot from a request's Host.
+func HostToHostname(host string) string {
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+
+	return strings.TrimSuffix(host, ".")
+}
+
+var hostnameSegmentSyntax = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+var disallowedTopLevelDomains = []string{"alt", "arpa", "example", "internal", "invalid", "local", "localhost", "onion"}
+
+// ValidateHostname checks a lowercase hostname against the atproto handle
+// syntax (https://atproto.com/specs/handle#handle-identifier-syntax).
+func ValidateHostname(hostname string) error {
+	if len(hostname) > 253 {
+		return errors.New("must not be longer than 253 characters")
+	}
+
+	segments := strings.Split(hostname, ".")
+
+	if len(segments) < 2 {
+		return errors.New("must have at least two segments separated by `.`")
+	}
+
+	for _, segment := range segments {
+		if len(segment) < 1 || len(segment) > 63 {
+			return fmt.Errorf("segment %q must be between 1 and 63 characters", segment)
+		}
+
+		if !hostnameSegmentSyntax.MatchString(segment) {
+			return fmt.Errorf("segment %q must only contain a-z, 0-9 and `-` (not at the start or end)", segment)
+		}
+	}
+
+	topLevelDomain := segments[len(segments)-1]
+
+	if topLevelDomain[0] >= '0' && topLevelDomain[0] <= '9' {
+		return fmt.Errorf("top level domain %q must not start with a digit", topLevelDomain)
+	}
+
+	if slices.Contains(disallowedTopLevelDomains, topLevelDomain) {
+		return fmt.Errorf("top level domain %q is not allowed", topLevelDomain)
+	}
+
+	return nil
+}
 
-func URLFromTemplate(
-	template URLTemplate,
-	request *http.Request,
-	handle Handle,
-	did DecentralizedID,
-) string {
-	replacements := map[string]string{
-		"{handle}":          handle.String(),
-		"{did}":             string(did),
-		"{handle.domain}":   string(handle.Domain),
-		"{handle.username}": string(handle.Username),
-		"{request.scheme}":  string(request.URL.Scheme),
-		"{request.host}":    string(request.Host),
-		"{request.path}":    string(request.URL.Path),
-		"{request.query}":   string(request.URL.RawQuery),
+// ValidateDomain checks a domain, which may be a wildcard (`*.example.com`)
+// providing for every domain below it.
+func ValidateDomain(domain Domain) error {
+	if err := ValidateHostname(strings.TrimPrefix(string(domain), "*.")); err != nil {
+		return fmt.Errorf("Domain %s is not valid: %w", domain, err)
 	}
 
-	url := string(template)
+	return nil
+}
+
+var decentralizedIDSyntax = regexp.MustCompile(`^did:[a-z]+:[a-zA-Z0-9._:%-]*[a-zA-Z0-9._-]$`)
 
-	for token, value := range replacements {
-		url = strings.ReplaceAll(url, token, value)
+// ValidateDecentralizedID checks a Decentralized ID against the atproto DID
+// syntax (https://atproto.com/specs/did#at-protocol-did-identifier-syntax).
+func ValidateDecentralizedID(did DecentralizedID) error {
+	if len(did) > 2048 || !decentralizedIDSyntax.MatchString(string(did)) {
+		return fmt.Errorf("Decentralized ID %s is not valid", did)
 	}
 
-	return url
+	return nil
 }
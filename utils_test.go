@@ -1,8 +1,7 @@
 package main
 
 import (
-	"bytes"
-	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -55,45 +54,69 @@ func TestInvalidHostnameReturnsError(t *testing.T) {
 	assert.Equal(t, Handle{}, handle, "Handle returned for invalid hostname")
 }
 
-func TestTemplateUrlIsFormatted(t *testing.T) {
+func TestHostnameIsValidatedAgainstHandleSyntax(t *testing.T) {
 	tests := []struct {
-		template    URLTemplate
-		expectedUrl string
+		hostname string
+		valid    bool
 	}{
-		{
-			template:    "https://example.com/?handle={handle}",
-			expectedUrl: "https://example.com/?handle=alice.example.com",
-		},
-		{
-			template:    "https://{handle.domain}/?username={handle.username}",
-			expectedUrl: "https://example.com/?username=alice",
-		},
-		{
-			template:    "https://bsky.app/profile/{did}",
-			expectedUrl: "https://bsky.app/profile/did:plc:example",
-		},
-		{
-			template:    "https://example.com",
-			expectedUrl: "https://example.com",
-		},
-		{
-			template:    "https://example.com?{request.query}",
-			expectedUrl: "https://example.com?a=b",
-		},
+		{hostname: "alice.example.com", valid: true},
+		{hostname: "alice-1.example.com", valid: true},
+		{hostname: "xn--ls8h.test", valid: true},
+		{hostname: "alice.example.com.", valid: false},
+		{hostname: "-alice.example.com", valid: false},
+		{hostname: "alice-.example.com", valid: false},
+		{hostname: "alice_bob.example.com", valid: false},
+		{hostname: "alice..example.com", valid: false},
+		{hostname: "alicé.example.com", valid: false},
+		{hostname: strings.Repeat("a", 64) + ".example.com", valid: false},
+		{hostname: strings.Repeat("abcdefghi.", 26) + "com", valid: false},
+		{hostname: "alice.example.123", valid: false},
+		{hostname: "alice.local", valid: false},
+		{hostname: "alice.in-addr.arpa", valid: false},
+		{hostname: "alice.onion", valid: false},
+		{hostname: "alice.example", valid: false},
+	}
+
+	for _, test := range tests {
+		_, err := HostnameToHandle(test.hostname)
+
+		assert.Equal(t, test.valid, err == nil, "Hostname %s validity (%v)", test.hostname, err)
 	}
+}
+
+func TestHostIsConvertedToHostname(t *testing.T) {
+	assert.Equal(t, "alice.example.com", HostToHostname("alice.example.com:8080"))
+	assert.Equal(t, "alice.example.com", HostToHostname("alice.example.com."))
+	assert.Equal(t, "alice.example.com", HostToHostname("alice.example.com"))
+}
 
-	request, _ := http.NewRequest("GET", "https://alice.example.com?a=b", bytes.NewReader([]byte{}))
-	handle := Handle{Domain: "example.com", Username: "alice"}
-	did := DecentralizedID("did:plc:example")
+func TestDecentralizedIDIsValidatedAgainstDIDSyntax(t *testing.T) {
+	tests := []struct {
+		did   DecentralizedID
+		valid bool
+	}{
+		{did: "did:plc:z72i7hdynmk6r22z27h6tvur", valid: true},
+		{did: "did:web:alice.example.com", valid: true},
+		{did: "did:web:localhost%3A8080", valid: true},
+		{did: "did:plc:", valid: false},
+		{did: "did:PLC:example", valid: false},
+		{did: "did:plc:example:", valid: false},
+		{did: "did:plc:example%", valid: false},
+		{did: "plc:example", valid: false},
+		{did: "did:plc:exa mple", valid: false},
+		{did: DecentralizedID("did:plc:" + strings.Repeat("a", 2048)), valid: false},
+	}
 
 	for _, test := range tests {
-		url := URLFromTemplate(test.template, request, handle, did)
-		assert.Equal(
-			t,
-			test.expectedUrl,
-			url,
-			"Template %s was not formatted correctly",
-			url,
-		)
+		err := ValidateDecentralizedID(test.did)
+
+		assert.Equal(t, test.valid, err == nil, "Decentralized ID %s validity", test.did)
 	}
 }
+
+func TestWildcardDomainsAreValid(t *testing.T) {
+	assert.NoError(t, ValidateDomain("*.example.com"))
+	assert.Error(t, ValidateDomain("*.com"))
+	assert.Error(t, ValidateDomain("*.*.example.com"))
+	assert.Error(t, ValidateDomain("alice.*.example.com"))
+}
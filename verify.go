@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+type DIDDocument struct {
+	ID                 string               `json:"id" yaml:"id"`
+	AlsoKnownAs        []string             `json:"alsoKnownAs" yaml:"alsoKnownAs"`
+	VerificationMethod []VerificationMethod `json:"verificationMethod,omitempty" yaml:"verificationMethod"`
+	Service            []DIDService         `json:"service,omitempty" yaml:"service"`
+}
+
+// DIDDocumentResolver resolves did:plc documents from a PLC directory and
+// did:web documents from the `/.well-known/did.json` of their host.
+type DIDDocumentResolver struct {
+	plcDirectory string
+	client       *http.Client
+}
+
+func NewDIDDocumentResolver(plcDirectory string, client *http.Client) *DIDDocumentResolver {
+	return &DIDDocumentResolver{strings.TrimSuffix(plcDirectory, "/"), client}
+}
+
+func (resolver *DIDDocumentResolver) Resolve(ctx context.Context, did DecentralizedID) (DIDDocument, error) {
+	var location string
+
+	switch {
+	case strings.HasPrefix(string(did), "did:plc:"):
+		location = fmt.Sprintf("%s/%s", resolver.plcDirectory, did)
+	case strings.HasPrefix(string(did), "did:web:"):
+		host, err := url.PathUnescape(strings.TrimPrefix(string(did), "did:web:"))
+
+		if err != nil {
+			return DIDDocument{}, err
+		}
+
+		location = fmt.Sprintf("https://%s/.well-known/did.json", host)
+	default:
+		return DIDDocument{}, fmt.Errorf("Decentralized ID %s does not use a supported method (plc, web)", did)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+
+	if err != nil {
+		return DIDDocument{}, err
+	}
+
+	response, err := resolver.client.Do(request)
+
+	if err != nil {
+		return DIDDocument{}, err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return DIDDocument{}, fmt.Errorf("document for %s could not be resolved from %s (%s)", did, location, response.Status)
+	}
+
+	var document DIDDocument
+
+	if err := json.NewDecoder(io.LimitReader(response.Body, 1<<20)).Decode(&document); err != nil {
+		return DIDDocument{}, fmt.Errorf("document for %s is not valid: %w", did, err)
+	}
+
+	if document.ID != string(did) {
+		return DIDDocument{}, fmt.Errorf("document for %s has a different id %s", did, document.ID)
+	}
+
+	return document, nil
+}
+
+type Verification struct {
+	Handle    string          `json:"handle"`
+	DID       DecentralizedID `json:"did"`
+	Verified  bool            `json:"verified"`
+	Reason    string          `json:"reason,omitempty"`
+	CheckedAt time.Time       `json:"checkedAt"`
+}
+
+// HandleVerifier checks that the DID document of a handle's Decentralized ID
+// claims the handle (`at://<handle>` in `alsoKnownAs`) and remembers the
+// results of the most recently verified handles for the interval.
+type HandleVerifier struct {
+	resolver *DIDDocumentResolver
+	interval time.Duration
+	logger   *slog.Logger
+	results  *expiringCache[Verification]
+
+	mutex    sync.Mutex
+	checking map[string]bool
+}
+
+func NewHandleVerifier(resolver *DIDDocumentResolver, interval time.Duration, size int, logger *slog.Logger) *HandleVerifier {
+	return &HandleVerifier{
+		resolver: resolver,
+		interval: interval,
+		logger:   logger,
+		results:  newExpiringCache[Verification](size),
+		checking: make(map[string]bool),
+	}
+}
+
+func (verifier *HandleVerifier) Verify(ctx context.Context, handle Handle, did DecentralizedID) Verification {
+	verification := Verification{Handle: handle.String(), DID: did, CheckedAt: time.Now()}
+
+	document, err := verifier.resolver.Resolve(ctx, did)
+
+	switch {
+	case err != nil:
+		verification.Reason = err.Error()
+	case !slices.Contains(document.AlsoKnownAs, "at://"+handle.String()):
+		verification.Reason = fmt.Sprintf("document for %s does not claim at://%s", did, handle)
+	default:
+		verification.Verified = true
+	}
+
+	if !verification.Verified {
+		verifier.logger.Warn("handle could not be verified", "handle", verification.Handle, "did", did, "reason", verification.Reason)
+	}
+
+	verifier.results.set(verification.Handle, verification, verifier.interval)
+
+	return verification
+}
+
+// VerifyInBackground verifies a handle unless it is being verified or was
+// verified with the same Decentralized ID within the interval.
+func (verifier *HandleVerifier) VerifyInBackground(handle Handle, did DecentralizedID) {
+	verifier.mutex.Lock()
+	defer verifier.mutex.Unlock()
+
+	previous, verified := verifier.results.get(handle.String())
+
+	if verifier.checking[handle.String()] || (verified && previous.DID == did) {
+		return
+	}
+
+	verifier.checking[handle.String()] = true
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		verifier.Verify(ctx, handle, did)
+
+		verifier.mutex.Lock()
+		defer verifier.mutex.Unlock()
+
+		delete(verifier.checking, handle.String())
+	}()
+}
+
+func (verifier *HandleVerifier) Results() []Verification {
+	results := verifier.results.values()
+
+	slices.SortFunc(results, func(a, b Verification) int { return strings.Compare(a.Handle, b.Handle) })
+
+	return results
+}
+
+// VerifyingProvider verifies every Decentralized ID it provides in the
+// background; results are reported but never change what is provided.
+type VerifyingProvider struct {
+	provider ProvidesDecentralizedIDs
+	verifier *HandleVerifier
+}
+
+func NewVerifyingProvider(provider ProvidesDecentralizedIDs, verifier *HandleVerifier) *VerifyingProvider {
+	return &VerifyingProvider{provider, verifier}
+}
+
+func (verifying *VerifyingProvider) GetDecentralizedIDForHandle(ctx context.Context, handle Handle) (DecentralizedID, error) {
+	did, err := verifying.provider.GetDecentralizedIDForHandle(ctx, handle)
+
+	if err == nil && did != "" {
+		verifying.verifier.VerifyInBackground(handle, did)
+	}
+
+	return did, err
+}
+
+func (verifying *VerifyingProvider) CanProvideForDomain(ctx context.Context, domain Domain) (bool, error) {
+	return verifying.provider.CanProvideForDomain(ctx, domain)
+}
+
+func (verifying *VerifyingProvider) IsHealthy(ctx context.Context) (bool, string) {
+	return verifying.provider.IsHealthy(ctx)
+}
+
+func (verifying *VerifyingProvider) Unwrap() ProvidesDecentralizedIDs {
+	return verifying.provider
+}
+
+func (verifying *VerifyingProvider) Close() error {
+	if closer, ok := verifying.provider.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
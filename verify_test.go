@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func NewTestPLCDirectory(t *testing.T) *httptest.Server {
+	documents := map[string]string{
+		"/did:plc:example001": `{"id": "did:plc:example001", "alsoKnownAs": ["at://alice.example.com"]}`,
+		"/did:plc:example002": `{"id": "did:plc:example002", "alsoKnownAs": ["at://someone.example.net"]}`,
+		"/did:plc:example003": `{"id": "did:plc:example999", "alsoKnownAs": ["at://carol.example.com"]}`,
+	}
+
+	directory := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		document, ok := documents[r.URL.Path]
+
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		_, _ = w.Write([]byte(document))
+	}))
+
+	t.Cleanup(directory.Close)
+
+	return directory
+}
+
+func NewTestHandleVerifier(t *testing.T) *HandleVerifier {
+	directory := NewTestPLCDirectory(t)
+
+	return NewHandleVerifier(NewDIDDocumentResolver(directory.URL+"/", directory.Client()), time.Hour, 10, testFileLogger)
+}
+
+func TestHandleIsVerifiedWhenDocumentClaimsHandle(t *testing.T) {
+	verifier := NewTestHandleVerifier(t)
+
+	verification := verifier.Verify(context.Background(), Handle{Domain: "example.com", Username: "alice"}, "did:plc:example001")
+
+	assert.True(t, verification.Verified)
+	assert.Empty(t, verification.Reason)
+}
+
+func TestHandleIsNotVerifiedWhenDocumentDoesNotClaimHandle(t *testing.T) {
+	tests := []struct {
+		handle Handle
+		did    DecentralizedID
+	}{
+		{handle: Handle{Domain: "example.com", Username: "bob"}, did: "did:plc:example002"},
+		{handle: Handle{Domain: "example.com", Username: "carol"}, did: "did:plc:example003"},
+		{handle: Handle{Domain: "example.com", Username: "dave"}, did: "did:plc:example404"},
+		{handle: Handle{Domain: "example.com", Username: "erin"}, did: "did:key:example"},
+	}
+
+	verifier := NewTestHandleVerifier(t)
+
+	for _, test := range tests {
+		verification := verifier.Verify(context.Background(), test.handle, test.did)
+
+		assert.False(t, verification.Verified, "%s was verified using %s", test.handle, test.did)
+		assert.NotEmpty(t, verification.Reason)
+	}
+}
+
+func TestVerifierOnlyRemembersRecentResults(t *testing.T) {
+	directory := NewTestPLCDirectory(t)
+	verifier := NewHandleVerifier(NewDIDDocumentResolver(directory.URL+"/", directory.Client()), time.Hour, 2, testFileLogger)
+
+	now := time.Now()
+	verifier.results.now = func() time.Time { return now }
+
+	for _, username := range []Username{"alice", "bob", "carol"} {
+		verifier.Verify(context.Background(), Handle{Domain: "example.com", Username: username}, "did:plc:example001")
+	}
+
+	results := verifier.Results()
+
+	assert.Len(t, results, 2)
+	assert.Equal(t, "bob.example.com", results[0].Handle)
+	assert.Equal(t, "carol.example.com", results[1].Handle)
+
+	now = now.Add(time.Hour)
+
+	assert.Empty(t, verifier.Results())
+}
+
+func TestVerifyingProviderVerifiesProvidedHandlesInBackground(t *testing.T) {
+	verifier := NewTestHandleVerifier(t)
+	provider := NewVerifyingProvider(NewInMemoryProvider(map[Hostname]DecentralizedID{
+		"alice.example.com": "did:plc:example001",
+		"bob.example.com":   "did:plc:example002",
+	}, map[Domain]bool{
+		"example.com": true,
+	}), verifier)
+
+	for _, username := range []Username{"alice", "bob", "carol"} {
+		_, _ = provider.GetDecentralizedIDForHandle(context.Background(), Handle{Domain: "example.com", Username: username})
+	}
+
+	assert.Eventually(t, func() bool { return len(verifier.Results()) == 2 }, time.Second, 10*time.Millisecond)
+
+	results := verifier.Results()
+
+	assert.Equal(t, "alice.example.com", results[0].Handle)
+	assert.True(t, results[0].Verified)
+	assert.Equal(t, "bob.example.com", results[1].Handle)
+	assert.False(t, results[1].Verified)
+}
+
+func TestVerificationEndpointReportsUnverifiedHandles(t *testing.T) {
+	verifier := NewTestHandleVerifier(t)
+	verifier.Verify(context.Background(), Handle{Domain: "example.com", Username: "alice"}, "did:plc:example001")
+	verifier.Verify(context.Background(), Handle{Domain: "example.com", Username: "bob"}, "did:plc:example002")
+
+	router := gin.New()
+	AddApplicationRoutes(router, Config{
+		Provider:   NewVerifyingProvider(newTestAdminProvider(), verifier),
+		Logger:     testFileLogger,
+		AdminToken: "secret",
+		Verifier:   verifier,
+	})
+
+	res := adminRequest(router, "GET", "/admin/verification?unverified=true", "")
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Contains(t, res.Body.String(), `"handle":"bob.example.com"`)
+	assert.NotContains(t, res.Body.String(), `"handle":"alice.example.com"`)
+
+	res = adminRequest(router, "GET", "/admin/handles", "")
+
+	assert.Equal(t, http.StatusOK, res.Code, "Admin API is not available through verifying provider")
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type XRPCError struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+// ResolveHandle answers com.atproto.identity.resolveHandle using the same
+// provider as the well-known method.
+func ResolveHandle(provider ProvidesDecentralizedIDs) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		hostname := c.Query("handle")
+
+		if hostname == "" {
+			c.JSON(http.StatusBadRequest, XRPCError{"InvalidRequest", `Error: Params must have the property "handle"`})
+			return
+		}
+
+		handle, err := HostnameToHandle(hostname)
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, XRPCError{"InvalidRequest", err.Error()})
+			return
+		}
+
+		var did DecentralizedID
+
+		// Errors of either are answered as the provider's errors below.
+		handle, err = HandleOnProvidedDomain(c, provider, handle)
+
+		if err == nil {
+			did, err = GetValidDecentralizedIDForHandle(c, provider, handle)
+		}
+
+		if errors.Is(err, (*CannotGetHandelsFromDomainError)(nil)) {
+			c.JSON(http.StatusBadRequest, XRPCError{"HandleNotFound", "Unable to resolve handle"})
+			return
+		}
+
+		if err != nil {
+			_ = c.Error(err)
+			c.JSON(http.StatusBadGateway, XRPCError{"UpstreamFailure", fmt.Sprintf("Unable to resolve handle %s", handle)})
+			return
+		}
+
+		if did == "" {
+			c.JSON(http.StatusBadRequest, XRPCError{"HandleNotFound", "Unable to resolve handle"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"did": did})
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveHandleReturnsDidForKnownHandle(t *testing.T) {
+	router, _ := NewTestEnvironment()
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "https://handles.example.net/xrpc/com.atproto.identity.resolveHandle?handle=Alice.example.com", nil)
+	router.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.JSONEq(t, `{"did": "did:plc:example001"}`, res.Body.String())
+}
+
+func TestResolveHandleReturnsStandardErrors(t *testing.T) {
+	tests := []struct {
+		query          string
+		expectedStatus int
+		expectedError  string
+	}{
+		{query: "", expectedStatus: http.StatusBadRequest, expectedError: "InvalidRequest"},
+		{query: "?handle=alice", expectedStatus: http.StatusBadRequest, expectedError: "InvalidRequest"},
+		{query: "?handle=carol.example.com", expectedStatus: http.StatusBadRequest, expectedError: "HandleNotFound"},
+		{query: "?handle=alice.unprovided.test", expectedStatus: http.StatusBadRequest, expectedError: "HandleNotFound"},
+	}
+
+	router, _ := NewTestEnvironment()
+
+	for _, test := range tests {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/xrpc/com.atproto.identity.resolveHandle"+test.query, nil)
+		router.ServeHTTP(res, req)
+
+		assert.Equal(t, test.expectedStatus, res.Code, "Query %s", test.query)
+		assert.Contains(t, res.Body.String(), `"error":"`+test.expectedError+`"`, "Query %s", test.query)
+	}
+}
+
+func TestResolveHandleReturnsDidForNestedUsername(t *testing.T) {
+	router, provider := NewTestEnvironment()
+
+	_ = provider.SetDecentralizedIDForHandle(context.Background(), Handle{Domain: "example.com", Username: "carol.team"}, "did:plc:example003")
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "https://handles.example.net/xrpc/com.atproto.identity.resolveHandle?handle=carol.team.example.com", nil)
+	router.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.JSONEq(t, `{"did": "did:plc:example003"}`, res.Body.String())
+}